@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cli/go-gh/pkg/tableprinter"
+	"github.com/cli/go-gh/pkg/term"
+	"github.com/cli/go-gh/pkg/text"
+)
+
+// Answer struct represents the accepted answer of a Q&A discussion
+type Answer struct {
+	Body   string `json:"body"`
+	URL    string `json:"url"`
+	Author struct {
+		Login string `json:"login"`
+	} `json:"author"`
+}
+
+// Query fragment for the accepted answer, fetched only when needed
+func answerQuery(flags Flags) string {
+	if !flags.answerOnly && !flags.answersFeed && flags.sortBy != "answered" {
+		return ""
+	}
+	return "answerChosenAt answer { body url author { login } }"
+}
+
+// When the answer was chosen; the zero time if unanswered or not fetched
+func answerTime(d Discussion) time.Time {
+	if d.AnswerChosenAt == nil {
+		return time.Time{}
+	}
+	return *d.AnswerChosenAt
+}
+
+// Only the discussions that have an accepted answer
+func answeredDiscussions(discussions []Discussion) []Discussion {
+	answered := []Discussion{}
+	for _, d := range discussions {
+		if d.Answer != nil {
+			answered = append(answered, d)
+		}
+	}
+	return answered
+}
+
+// Print each accepted answer under a reference to its question
+func outputAnswers(out io.Writer, matches []Discussion, flags Flags) error {
+	color := colorEnabled(flags.color)
+	for i, d := range matches {
+		if i > 0 {
+			fmt.Fprintln(out, "\n---")
+		}
+		fmt.Fprintf(out, "Q: %s\n%s\n\n", discussionTitle(d, flags), d.URL)
+		fmt.Fprintf(out, "A (@%s): %s\n\n", d.Answer.Author.Login, d.Answer.URL)
+		body := strings.TrimSpace(d.Answer.Body)
+		if flags.render {
+			rendered, err := renderMarkdown(body, flags)
+			if err != nil {
+				return fmt.Errorf("could not render Markdown: %w", err)
+			}
+			body = rendered
+		}
+		fmt.Fprintln(out, highlight(body, flags, color))
+	}
+	return nil
+}
+
+// Print answered matches with when they were answered and an answer excerpt
+func outputAnswersFeed(matches []Discussion, flags Flags) error {
+	tp := tableprinter.New(stdout, term.IsTerminal(os.Stdout), 100)
+	now := time.Now()
+	for _, d := range matches {
+		tp.AddField(text.RelativeTimeAgo(now, answerTime(d)))
+		tp.AddField(discussionTitle(d, flags))
+		tp.AddField(leadSnippet(proseText(d.Answer.Body, flags)))
+		tp.AddField(d.Answer.URL)
+		tp.EndRow()
+	}
+	return tp.Render()
+}
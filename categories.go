@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/cli/go-gh/pkg/api"
+	"github.com/cli/go-gh/pkg/repository"
+	"github.com/cli/go-gh/pkg/tableprinter"
+	"github.com/cli/go-gh/pkg/term"
+)
+
+// DiscussionCategory describes one of a repository's discussion categories
+type DiscussionCategory struct {
+	Name         string `json:"name"`
+	Emoji        string `json:"emoji"`
+	Description  string `json:"description"`
+	IsAnswerable bool   `json:"isAnswerable"`
+}
+
+// Fetch and print the repository's discussion categories
+func listCategories(client api.GQLClient, repo repository.Repository, flags Flags) error {
+	var response struct {
+		Repository struct {
+			HasDiscussionsEnabled bool
+			DiscussionCategories  struct {
+				Nodes []DiscussionCategory
+			}
+		}
+	}
+	query := fmt.Sprintf(`{
+		repository(owner: "%s", name: "%s") {
+			hasDiscussionsEnabled
+			discussionCategories(first: 100) {
+				nodes { name emoji description isAnswerable }
+	}}}`, repo.Owner(), repo.Name())
+	err := mapGraphQLError(client.Do(query, nil, &response))
+	if errors.Is(err, errRepoNotFound) {
+		return fmt.Errorf("%s/%s: %w", repo.Owner(), repo.Name(), err)
+	} else if err != nil {
+		return fmt.Errorf("failed to talk to the GitHub API: %w", err)
+	}
+	if !response.Repository.HasDiscussionsEnabled {
+		return fmt.Errorf("%s/%s %w", repo.Owner(), repo.Name(), errDiscussionsDisabled)
+	}
+	categories := response.Repository.DiscussionCategories.Nodes
+
+	if flags.jsonFlag {
+		return handleJSONOutput(categories, flags)
+	}
+
+	tp := tableprinter.New(stdout, term.IsTerminal(os.Stdout), 100)
+	for _, c := range categories {
+		tp.AddField(c.Emoji)
+		tp.AddField(c.Name)
+		if c.IsAnswerable {
+			tp.AddField("answerable")
+		} else {
+			tp.AddField("")
+		}
+		tp.AddField(c.Description)
+		tp.EndRow()
+	}
+	return tp.Render()
+}
@@ -0,0 +1,123 @@
+package main
+
+import "time"
+
+// Comments holds the comments fetched for a discussion
+type Comments struct {
+	Nodes []Comment `json:"nodes"`
+}
+
+// Comment struct represents a top-level discussion comment
+type Comment struct {
+	Body    string   `json:"body"`
+	Replies *Replies `json:"replies,omitempty"`
+}
+
+// Replies holds the replies fetched for a comment
+type Replies struct {
+	Nodes []Reply `json:"nodes"`
+}
+
+// Reply struct represents a reply to a discussion comment
+type Reply struct {
+	Body string `json:"body"`
+}
+
+// LastComment holds the most recent comment on a discussion
+type LastComment struct {
+	Nodes []struct {
+		CreatedAt time.Time `json:"createdAt"`
+	} `json:"nodes"`
+}
+
+// Query fragment for the most recent comment, fetched only when activity
+// times are needed
+func lastCommentQuery(flags Flags) string {
+	if flags.activeSince == 0 && !hasField(flags.fields, "lastActive") {
+		return ""
+	}
+	return "lastComment: comments(last: 1) { nodes { createdAt } }"
+}
+
+// Query fragment for comments down to the requested depth. Replies multiply
+// the number of nodes requested, so they are only fetched at depth 2.
+func commentsQuery(depth int) string {
+	switch depth {
+	case 1:
+		return "comments(first: 20) { nodes { body } }"
+	case 2:
+		return "comments(first: 20) { nodes { body replies(first: 10) { nodes { body } } } }"
+	}
+	return ""
+}
+
+// Report whether the search term first occurs in a "comment" or a "reply"
+func matchCommentLocation(comments *Comments, search string, flags Flags) string {
+	if comments == nil {
+		return ""
+	}
+	for _, c := range comments.Nodes {
+		if containsMatch(proseText(c.Body, flags), search, flags) {
+			return "comment"
+		}
+	}
+	for _, c := range comments.Nodes {
+		if c.Replies == nil {
+			continue
+		}
+		for _, r := range c.Replies.Nodes {
+			if containsMatch(proseText(r.Body, flags), search, flags) {
+				return "reply"
+			}
+		}
+	}
+	return ""
+}
+
+// Trim each discussion's comments to those that matched the search, along
+// with only their matching replies. A comment is kept if it or any of its
+// replies matched.
+func withMatchedComments(discussions []Discussion, flags Flags) []Discussion {
+	search := normalizeText(flags.searchTerm, flags)
+	trimmed := make([]Discussion, 0, len(discussions))
+	for _, d := range discussions {
+		if d.Comments != nil {
+			kept := []Comment{}
+			for _, c := range d.Comments.Nodes {
+				if c.Replies != nil {
+					replies := []Reply{}
+					for _, r := range c.Replies.Nodes {
+						if containsMatch(proseText(r.Body, flags), search, flags) {
+							replies = append(replies, r)
+						}
+					}
+					c.Replies = &Replies{Nodes: replies}
+				}
+				if containsMatch(proseText(c.Body, flags), search, flags) || c.Replies != nil && len(c.Replies.Nodes) > 0 {
+					kept = append(kept, c)
+				}
+			}
+			d.Comments = &Comments{Nodes: kept}
+		}
+		trimmed = append(trimmed, d)
+	}
+	return trimmed
+}
+
+// Count occurrences of the search term across comments and replies
+func countInComments(comments *Comments, search string, flags Flags) int {
+	if comments == nil {
+		return 0
+	}
+	count := 0
+	for _, c := range comments.Nodes {
+		count += countMatches(proseText(c.Body, flags), search, flags)
+		if c.Replies == nil {
+			continue
+		}
+		for _, r := range c.Replies.Nodes {
+			count += countMatches(proseText(r.Body, flags), search, flags)
+		}
+	}
+	return count
+}
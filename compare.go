@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cli/go-gh/pkg/api"
+	"github.com/cli/go-gh/pkg/repository"
+	"github.com/cli/go-gh/pkg/tableprinter"
+	"github.com/cli/go-gh/pkg/term"
+)
+
+// Titles whose word sets overlap at least this much are considered the same
+const titleSimilarityThreshold = 0.8
+
+// CompareResult lists matches that have no counterpart in the other repository
+type CompareResult struct {
+	RepoA   string       `json:"repoA"`
+	RepoB   string       `json:"repoB"`
+	OnlyInA []Discussion `json:"onlyInA"`
+	OnlyInB []Discussion `json:"onlyInB"`
+}
+
+// Search two repositories and report matches that exist in only one of them
+func runCompare(client api.GQLClient, a, b repository.Repository, flags Flags, warnings *Warnings) error {
+	resultA, err := searchDiscussions(client, a, flags, warnings)
+	if err != nil {
+		return err
+	}
+	resultB, err := searchDiscussions(client, b, flags, warnings)
+	if err != nil {
+		return err
+	}
+	matchesA, matchesB := resultA.Matches, resultB.Matches
+	sortDiscussions(matchesA, flags.sortBy)
+	sortDiscussions(matchesB, flags.sortBy)
+
+	result := CompareResult{
+		RepoA:   fmt.Sprintf("%s/%s", a.Owner(), a.Name()),
+		RepoB:   fmt.Sprintf("%s/%s", b.Owner(), b.Name()),
+		OnlyInA: onlyIn(matchesA, matchesB),
+		OnlyInB: onlyIn(matchesB, matchesA),
+	}
+
+	if flags.jsonFlag {
+		return handleJSONOutput(result, flags)
+	}
+
+	isTerminal := term.IsTerminal(os.Stdout)
+	sections := []struct {
+		repo    string
+		matches []Discussion
+	}{
+		{result.RepoA, result.OnlyInA},
+		{result.RepoB, result.OnlyInB},
+	}
+	for i, section := range sections {
+		if i > 0 {
+			fmt.Fprintln(stdout)
+		}
+		fmt.Fprintf(stdout, "Only in %s (%d)\n", section.repo, len(section.matches))
+		tp := tableprinter.New(stdout, isTerminal, 100)
+		for _, d := range section.matches {
+			tp.AddField(discussionTitle(d, flags))
+			tp.AddField(d.URL)
+			tp.EndRow()
+		}
+		if err := tp.Render(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Discussions in from that have no similarly titled discussion in other
+func onlyIn(from, other []Discussion) []Discussion {
+	result := []Discussion{}
+	for _, d := range from {
+		found := false
+		for _, o := range other {
+			if titleSimilarity(d.Title, o.Title) >= titleSimilarityThreshold {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+// Fold discussions with similar titles into the first of them, recording
+// the URLs of the rest as duplicates
+func collapseDuplicates(discussions []Discussion) []Discussion {
+	collapsed := []Discussion{}
+	for _, d := range discussions {
+		duplicate := false
+		for i := range collapsed {
+			if titleSimilarity(collapsed[i].Title, d.Title) >= titleSimilarityThreshold {
+				collapsed[i].DuplicateURLs = append(collapsed[i].DuplicateURLs, d.URL)
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			collapsed = append(collapsed, d)
+		}
+	}
+	return collapsed
+}
+
+// Similarity of two titles as the Jaccard index of their normalized word
+// sets: 1 for the same words in any order, 0 for no words in common
+func titleSimilarity(a, b string) float64 {
+	wordsA, wordsB := titleWords(a), titleWords(b)
+	if len(wordsA) == 0 && len(wordsB) == 0 {
+		return 1
+	}
+	shared := 0
+	for w := range wordsA {
+		if wordsB[w] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(wordsA)+len(wordsB)-shared)
+}
+
+// Lowercased, accent-folded set of words in a title, ignoring punctuation
+func titleWords(title string) map[string]bool {
+	words := map[string]bool{}
+	for _, w := range splitWords(strings.ToLower(foldAccents(title))) {
+		words[w] = true
+	}
+	return words
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cli/go-gh/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds settings read from the gh-ask configuration file
+type Config struct {
+	Triage TriagePolicy `yaml:"triage"`
+}
+
+// TriagePolicy is the set of conditions --needs-triage matches. A
+// discussion needs triage when it meets every configured condition.
+type TriagePolicy struct {
+	// Unanswered requires the discussion to have no accepted answer
+	Unanswered bool `yaml:"unanswered"`
+	// Labels requires at least one of these labels
+	Labels []string `yaml:"labels"`
+	// WithoutLabels requires none of these labels
+	WithoutLabels []string `yaml:"withoutLabels"`
+}
+
+// Used when the configuration file does not exist
+var defaultConfig = Config{
+	Triage: TriagePolicy{Unanswered: true},
+}
+
+// Default location of the configuration file, alongside gh's own
+func defaultConfigPath() string {
+	return filepath.Join(config.ConfigDir(), "gh-ask.yml")
+}
+
+// Read the configuration file at path, or else at the default location. A
+// missing file at the default location yields the defaults; a missing file
+// that was asked for is an error.
+func readConfig(path string) (Config, error) {
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigPath()
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !explicit {
+		return defaultConfig, nil
+	} else if err != nil {
+		return Config{}, fmt.Errorf("could not read config: %w", err)
+	}
+	cfg := defaultConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("could not parse config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Check whether a discussion meets the triage policy
+func (p TriagePolicy) Matches(d Discussion) bool {
+	if p.Unanswered && d.IsAnswered {
+		return false
+	}
+	if len(p.Labels) > 0 && !hasAnyLabel(d, p.Labels) {
+		return false
+	}
+	if hasAnyLabel(d, p.WithoutLabels) {
+		return false
+	}
+	return true
+}
+
+// Check whether a discussion carries any of the labels, ignoring case
+func hasAnyLabel(d Discussion, labels []string) bool {
+	for _, l := range d.Labels.Nodes {
+		for _, want := range labels {
+			if strings.EqualFold(l.Name, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cli/go-gh/pkg/term"
+)
+
+// Estimate the rate limit points a search will cost, following GitHub's
+// formula: every connection costs one request per parent node, and each page
+// costs the total number of requests divided by 100, but at least 1
+func estimateQueryCost(flags Flags) int {
+	cost := 0
+	for remaining := flags.max; remaining > 0; remaining -= 100 {
+		n := remaining
+		if n > 100 {
+			n = 100
+		}
+		// The discussions connection plus labels on every discussion
+		requests := 1 + n
+		if flags.noBodyFetch {
+			requests = 1
+		}
+		if flags.commentsDepth >= 1 {
+			requests += n
+		}
+		if flags.commentsDepth == 2 {
+			requests += n * 20
+		}
+		if lastCommentQuery(flags) != "" {
+			requests += n
+		}
+		cost += (requests + 99) / 100
+	}
+	if flags.compare != "" {
+		cost *= 2
+	}
+	return cost
+}
+
+// Ask before running a search estimated to cost more than the
+// --confirm-large-query threshold, unless --yes was passed. Without a terminal
+// to ask on, the search only runs with --yes.
+func confirmLargeQuery(flags Flags) error {
+	cost := estimateQueryCost(flags)
+	if flags.confirmLarge == 0 || cost <= flags.confirmLarge || flags.yes {
+		return nil
+	}
+	if !term.IsTerminal(os.Stdin) || !term.IsTerminal(os.Stderr) {
+		return usageError{fmt.Errorf("this search would cost about %d rate limit points, more than --confirm-large-query %d; pass --yes to run it anyway",
+			cost, flags.confirmLarge)}
+	}
+
+	fmt.Fprintf(os.Stderr, "This search will cost about %d rate limit points. Continue? [y/N] ", cost)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		return fmt.Errorf("could not read confirmation: %w", err)
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	return errors.New("search cancelled")
+}
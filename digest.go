@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cli/go-gh/pkg/text"
+)
+
+// Digest summarizes a set of matches
+type Digest struct {
+	SearchTerm  string          `json:"searchTerm"`
+	Count       int             `json:"count"`
+	Categories  []CategoryCount `json:"categories"`
+	Answered    int             `json:"answered"`
+	Newest      time.Time       `json:"newest"`
+	MostUpvoted DigestEntry     `json:"mostUpvoted"`
+}
+
+// CategoryCount is the number of matches in one category
+type CategoryCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// DigestEntry identifies a single discussion in a digest
+type DigestEntry struct {
+	Title       string `json:"title"`
+	URL         string `json:"url"`
+	UpvoteCount int    `json:"upvoteCount"`
+}
+
+// Aggregate the matches into a digest; matches must not be empty
+func newDigest(matches []Discussion, flags Flags) Digest {
+	digest := Digest{SearchTerm: flags.searchTerm, Count: len(matches)}
+	counts := map[string]int{}
+	top := matches[0]
+	for _, d := range matches {
+		counts[d.Category.Name]++
+		if d.IsAnswered {
+			digest.Answered++
+		}
+		if d.CreatedAt.After(digest.Newest) {
+			digest.Newest = d.CreatedAt
+		}
+		if d.UpvoteCount > top.UpvoteCount {
+			top = d
+		}
+	}
+	for name, count := range counts {
+		digest.Categories = append(digest.Categories, CategoryCount{Name: name, Count: count})
+	}
+	// Largest categories first
+	sort.Slice(digest.Categories, func(i, j int) bool {
+		a, b := digest.Categories[i], digest.Categories[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Name < b.Name
+	})
+	digest.MostUpvoted = DigestEntry{Title: displayTitle(top.Title, flags), URL: top.URL, UpvoteCount: top.UpvoteCount}
+	return digest
+}
+
+// Render the digest as one sentence, e.g. "7 discussions about 'timeout'
+// across Q&A (4) and Ideas (3), 5 answered, newest 2 days ago, most upvoted:
+// <title>"
+func (d Digest) String() string {
+	var b strings.Builder
+	noun := "discussions"
+	if d.Count == 1 {
+		noun = "discussion"
+	}
+	fmt.Fprintf(&b, "%d %s", d.Count, noun)
+	if d.SearchTerm != "" {
+		fmt.Fprintf(&b, " about '%s'", d.SearchTerm)
+	}
+	if len(d.Categories) == 1 {
+		fmt.Fprintf(&b, " in %s", d.Categories[0].Name)
+	} else {
+		parts := make([]string, len(d.Categories))
+		for i, c := range d.Categories {
+			parts[i] = fmt.Sprintf("%s (%d)", c.Name, c.Count)
+		}
+		last := len(parts) - 1
+		fmt.Fprintf(&b, " across %s and %s", strings.Join(parts[:last], ", "), parts[last])
+	}
+	fmt.Fprintf(&b, ", %d answered, newest %s", d.Answered, text.RelativeTimeAgo(time.Now(), d.Newest))
+	if d.MostUpvoted.UpvoteCount > 0 {
+		fmt.Fprintf(&b, ", most upvoted: %s", d.MostUpvoted.Title)
+	}
+	return b.String()
+}
+
+// Print a digest of the matches, as a sentence or as JSON
+func outputDigest(matches []Discussion, flags Flags) error {
+	digest := newDigest(matches, flags)
+	if flags.jsonFlag {
+		return handleJSONOutput(digest, flags)
+	}
+	_, err := fmt.Fprintln(stdout, digest)
+	return err
+}
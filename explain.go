@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/cli/go-gh/pkg/tableprinter"
+	"github.com/cli/go-gh/pkg/term"
+)
+
+// Explanation records how one fetched discussion was judged
+type Explanation struct {
+	Number        int     `json:"number"`
+	Title         string  `json:"title"`
+	URL           string  `json:"url"`
+	Matched       bool    `json:"matched"`
+	RejectedBy    string  `json:"rejectedBy,omitempty"`
+	MatchLocation string  `json:"matchLocation,omitempty"`
+	Score         float64 `json:"score"`
+}
+
+// Explain the decision for every discussion in a page of results
+func explainDiscussions(discussions []Discussion, flags Flags) []Explanation {
+	search := normalizeText(flags.searchTerm, flags)
+	explanations := []Explanation{}
+	for _, d := range discussions {
+		rejectedBy := evaluateDiscussion(&d, search, flags)
+		explanations = append(explanations, Explanation{
+			Number:        d.Number,
+			Title:         d.Title,
+			URL:           d.URL,
+			Matched:       rejectedBy == "",
+			RejectedBy:    rejectedBy,
+			MatchLocation: d.MatchLocation,
+			Score:         d.Score,
+		})
+	}
+	return explanations
+}
+
+// Print explanations as JSON or as a table, highest score first
+func outputExplanations(explanations []Explanation, flags Flags) error {
+	sort.SliceStable(explanations, func(i, j int) bool {
+		return explanations[i].Score > explanations[j].Score
+	})
+	if flags.jsonFlag {
+		return handleJSONOutput(explanations, flags)
+	}
+
+	tp := tableprinter.New(stdout, term.IsTerminal(os.Stdout), 100)
+	for _, e := range explanations {
+		tp.AddField(strconv.FormatFloat(e.Score, 'g', -1, 64))
+		if e.Matched {
+			tp.AddField("matched")
+		} else {
+			tp.AddField(e.RejectedBy)
+		}
+		tp.AddField(displayTitle(e.Title, flags))
+		tp.AddField(e.URL)
+		tp.EndRow()
+	}
+	return tp.Render()
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// IssueTemplateHeader is the front matter of a GitHub issue template
+type IssueTemplateHeader struct {
+	Name   string   `yaml:"name"`
+	About  string   `yaml:"about"`
+	Title  string   `yaml:"title"`
+	Labels []string `yaml:"labels,omitempty"`
+}
+
+// Export each match as an issue template stub, to stdout or to one file per
+// match in outputDir
+func exportIssueTemplates(matches []Discussion, outputDir string) error {
+	if outputDir != "" {
+		if err := os.MkdirAll(outputDir, 0o755); err != nil {
+			return fmt.Errorf("could not create output directory: %w", err)
+		}
+	}
+
+	for i, d := range matches {
+		stub, err := renderIssueTemplate(d)
+		if err != nil {
+			return err
+		}
+		if outputDir == "" {
+			if i > 0 {
+				fmt.Fprintln(stdout)
+			}
+			fmt.Fprint(stdout, stub)
+			continue
+		}
+		path := filepath.Join(outputDir, fmt.Sprintf("%d-%s.md", d.Number, slugify(d.Title)))
+		if err := os.WriteFile(path, []byte(stub), 0o644); err != nil {
+			return fmt.Errorf("could not write issue template: %w", err)
+		}
+		fmt.Fprintln(os.Stderr, path)
+	}
+	return nil
+}
+
+// Render a discussion as issue template Markdown with YAML front matter
+func renderIssueTemplate(d Discussion) (string, error) {
+	header := IssueTemplateHeader{
+		Name:  d.Title,
+		About: fmt.Sprintf("Promoted from discussion %s", d.URL),
+		Title: d.Title,
+	}
+	for _, l := range d.Labels.Nodes {
+		header.Labels = append(header.Labels, l.Name)
+	}
+	frontMatter, err := yaml.Marshal(header)
+	if err != nil {
+		return "", fmt.Errorf("could not serialize issue template: %w", err)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "---\n%s---\n\n", frontMatter)
+	fmt.Fprintf(&b, "%s\n\n", strings.TrimSpace(d.Body))
+	fmt.Fprintf(&b, "_Originally discussed in %s_\n", d.URL)
+	return b.String(), nil
+}
+
+// Turn a title into a lowercase, dash-separated file name fragment
+func slugify(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteRune('-')
+			dash = true
+		}
+	}
+	slug := strings.TrimSuffix(b.String(), "-")
+	if len(slug) > 60 {
+		slug = strings.TrimRight(slug[:60], "-")
+	}
+	if slug == "" {
+		return "discussion"
+	}
+	return slug
+}
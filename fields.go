@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cli/go-gh/pkg/text"
+)
+
+// tableFields are the columns that can be selected with --fields
+var tableFields = map[string]func(d Discussion, flags Flags, now time.Time) string{
+	"title": func(d Discussion, flags Flags, _ time.Time) string {
+		return discussionTitle(d, flags)
+	},
+	"url": func(d Discussion, _ Flags, _ time.Time) string {
+		return strings.Join(append([]string{d.URL}, d.DuplicateURLs...), ", ")
+	},
+	"number": func(d Discussion, _ Flags, _ time.Time) string {
+		return strconv.Itoa(d.Number)
+	},
+	"author": func(d Discussion, flags Flags, _ time.Time) string {
+		return d.Author.Label(flags)
+	},
+	"category": func(d Discussion, _ Flags, _ time.Time) string {
+		return d.Category.Name
+	},
+	"createdAt": func(d Discussion, _ Flags, now time.Time) string {
+		return text.RelativeTimeAgo(now, d.CreatedAt)
+	},
+	"lastActive": func(d Discussion, _ Flags, now time.Time) string {
+		return text.RelativeTimeAgo(now, d.LastActive())
+	},
+	"score": func(d Discussion, _ Flags, _ time.Time) string {
+		return strconv.FormatFloat(d.Score, 'g', -1, 64)
+	},
+	"matchLocation": func(d Discussion, _ Flags, _ time.Time) string {
+		return d.MatchLocation
+	},
+	"participation": func(d Discussion, _ Flags, _ time.Time) string {
+		return strconv.Itoa(d.Participation)
+	},
+	"words": func(d Discussion, _ Flags, _ time.Time) string {
+		return strconv.Itoa(d.WordCount)
+	},
+	"snippet": func(d Discussion, flags Flags, _ time.Time) string {
+		return snippet(proseText(d.Body, flags), flags)
+	},
+}
+
+// Sorted names of the selectable table fields
+func fieldNames() []string {
+	names := make([]string, 0, len(tableFields))
+	for name := range tableFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Parse a comma-separated list of table fields
+func parseFields(s string) ([]string, error) {
+	fields := []string{}
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if _, ok := tableFields[name]; !ok {
+			return nil, fmt.Errorf("invalid field %q: must be one of %s", name, strings.Join(fieldNames(), ", "))
+		}
+		fields = append(fields, name)
+	}
+	return fields, nil
+}
+
+// Parse field aliases such as "createdAt=Date,url=Link"
+func parseFieldAliases(s string) (map[string]string, error) {
+	aliases := map[string]string{}
+	if s == "" {
+		return aliases, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		field, alias, ok := strings.Cut(pair, "=")
+		field, alias = strings.TrimSpace(field), strings.TrimSpace(alias)
+		if !ok || alias == "" {
+			return nil, fmt.Errorf("invalid field alias %q: expected FIELD=NAME", pair)
+		}
+		if _, ok := tableFields[field]; !ok {
+			return nil, fmt.Errorf("invalid field alias %q: unknown field %q", pair, field)
+		}
+		aliases[field] = alias
+	}
+	return aliases, nil
+}
+
+// Header for a table column, its alias if one was given
+func fieldHeader(name string, aliases map[string]string) string {
+	if alias, ok := aliases[name]; ok {
+		return alias
+	}
+	return name
+}
+
+// Check whether a field was selected
+func hasField(fields []string, name string) bool {
+	for _, f := range fields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse a duration, accepting day (d) and week (w) units in addition to
+// everything time.ParseDuration understands
+func parseDuration(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if strings.HasSuffix(s, suffix) {
+			count, err := strconv.Atoi(strings.TrimSuffix(s, suffix))
+			if err != nil || count < 0 {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cli/go-gh/pkg/markdown"
+	"github.com/cli/go-gh/pkg/term"
+)
+
+// Rendered Markdown wraps at this width when the terminal width is unknown
+const defaultRenderWidth = 80
+
+const (
+	highlightStart = "\x1b[1;33m"
+	highlightEnd   = "\x1b[0m"
+)
+
+// Print each match's title, URL, and full body
+func outputFullBodies(out io.Writer, matches []Discussion, flags Flags) error {
+	color := colorEnabled(flags.color)
+	for i, d := range matches {
+		if i > 0 {
+			fmt.Fprintln(out, "\n---")
+		}
+		fmt.Fprintf(out, "%s\n%s\n\n", highlight(discussionTitle(d, flags), flags, color), d.URL)
+		body := strings.TrimSpace(d.Body)
+		// Context comes from the text matching saw, so without code blocks
+		// under --ignore-code
+		if flags.contextLines > 0 {
+			if blocks := contextBlocks(proseText(body, flags), flags); len(blocks) > 0 {
+				body = strings.Join(blocks, "\n--\n")
+			} else if d.MatchLocation != "" && d.MatchLocation != "body" {
+				body = fmt.Sprintf("(matched in the %s)", d.MatchLocation)
+			}
+		}
+		if flags.render {
+			rendered, err := renderMarkdown(body, flags)
+			if err != nil {
+				return fmt.Errorf("could not render Markdown: %w", err)
+			}
+			body = rendered
+		}
+		// Highlighting goes last so rendering cannot strip it; highlight skips
+		// the escape sequences rendering adds
+		fmt.Fprintln(out, highlight(body, flags, color))
+	}
+	return nil
+}
+
+// Cut text down to blocks of the lines matching the search term, each with
+// up to flags.contextLines lines before and after it; overlapping blocks are
+// merged
+func contextBlocks(text string, flags Flags) []string {
+	search := normalizeText(flags.searchTerm, flags)
+	lines := strings.Split(text, "\n")
+	blocks := []string{}
+	start, end := -1, -1
+	for i, line := range lines {
+		if !containsMatch(line, search, flags) {
+			continue
+		}
+		from, to := i-flags.contextLines, i+flags.contextLines+1
+		if from < 0 {
+			from = 0
+		}
+		if to > len(lines) {
+			to = len(lines)
+		}
+		if start >= 0 && from > end {
+			blocks = append(blocks, strings.Join(lines[start:end], "\n"))
+			start = -1
+		}
+		if start < 0 {
+			start = from
+		}
+		end = to
+	}
+	if start >= 0 {
+		blocks = append(blocks, strings.Join(lines[start:end], "\n"))
+	}
+	return blocks
+}
+
+// Render Markdown for the terminal, wrapped at --render-width or else the
+// terminal's width
+func renderMarkdown(body string, flags Flags) (string, error) {
+	t := term.FromEnv()
+	width := flags.renderWidth
+	if width == 0 {
+		if w, _, err := t.Size(); err == nil && w > 0 {
+			width = w
+		} else {
+			width = defaultRenderWidth
+		}
+	}
+	theme := t.Theme()
+	if !colorEnabled(flags.color) {
+		theme = "none"
+	}
+	return markdown.Render(body, markdown.WithTheme(theme), markdown.WithWrap(width))
+}
+
+// Find the next occurrence of the normalized search term in haystack at or
+// after offset, returning its index and length, or -1. In prefix mode this is
+// the earliest word starting with any word of the term.
+func nextMatch(haystack string, offset int, search string, flags Flags) (int, int) {
+	if !flags.prefixMatch {
+		idx := strings.Index(haystack[offset:], search)
+		if idx < 0 {
+			return -1, 0
+		}
+		return offset + idx, len(search)
+	}
+	best, length := -1, 0
+	for _, token := range splitWords(search) {
+		for from := offset; from < len(haystack); {
+			idx := strings.Index(haystack[from:], token)
+			if idx < 0 {
+				break
+			}
+			idx += from
+			prev, _ := utf8.DecodeLastRuneInString(haystack[:idx])
+			if idx == 0 || !unicode.IsLetter(prev) && !unicode.IsDigit(prev) {
+				if best < 0 || idx < best {
+					best, length = idx, len(token)
+				}
+				break
+			}
+			from = idx + len(token)
+		}
+	}
+	return best, length
+}
+
+// Decide whether to emit ANSI color based on the --color value
+func colorEnabled(mode string) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	return term.FromEnv().IsColorEnabled()
+}
+
+// ANSI escape sequences, as emitted by the Markdown renderer
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]`)
+
+// Highlight occurrences of the search term in text, leaving any ANSI escape
+// sequences already in it untouched
+func highlight(text string, flags Flags, color bool) string {
+	search := normalizeText(flags.searchTerm, flags)
+	if !color || search == "" {
+		return text
+	}
+	var b strings.Builder
+	last := 0
+	for _, loc := range ansiEscape.FindAllStringIndex(text, -1) {
+		b.WriteString(highlightPlain(text[last:loc[0]], search, flags))
+		b.WriteString(text[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	b.WriteString(highlightPlain(text[last:], search, flags))
+	return b.String()
+}
+
+// Highlight occurrences of the normalized search term in text without escape
+// sequences. Matching happens on the normalized form of each rune so
+// highlighting agrees with --fold-accents, but the original text is what gets
+// printed.
+func highlightPlain(text, search string, flags Flags) string {
+	// Normalize rune by rune, remembering where each normalized byte came from
+	var normalized strings.Builder
+	origin := []int{}
+	for i, r := range text {
+		n := normalizeText(string(r), flags)
+		normalized.WriteString(n)
+		for range []byte(n) {
+			origin = append(origin, i)
+		}
+	}
+	origin = append(origin, len(text))
+	haystack := normalized.String()
+
+	var b strings.Builder
+	last := 0
+	for offset := 0; ; {
+		idx, n := nextMatch(haystack, offset, search, flags)
+		if idx < 0 {
+			break
+		}
+		start, end := origin[idx], origin[idx+n]
+		b.WriteString(text[last:start])
+		b.WriteString(highlightStart + text[start:end] + highlightEnd)
+		last = end
+		offset = idx + n
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
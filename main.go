@@ -6,8 +6,15 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
+	"runtime/debug"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/cli/go-gh"
 	"github.com/cli/go-gh/pkg/api"
@@ -17,15 +24,87 @@ import (
 	"github.com/cli/go-gh/pkg/repository"
 	"github.com/cli/go-gh/pkg/tableprinter"
 	"github.com/cli/go-gh/pkg/term"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
 )
 
 // Flags holds the parsed flag values
 type Flags struct {
-	jsonFlag     bool
-	jqFlag       string
-	lucky        bool
-	repoOverride string
-	searchTerm   string
+	activeSince     time.Duration
+	allComments     bool
+	announcements   bool
+	answerOnly      bool
+	answerableOnly  bool
+	author          string
+	answered        bool
+	answersFeed     bool
+	asIssueTemplate bool
+	category        string
+	cleanTitles     bool
+	collapseDups    bool
+	color           string
+	commentsDepth   int
+	exitCode        bool
+	compactJSON     bool
+	compare         string
+	digest          bool
+	config          Config
+	confirmLarge    int
+	contextLines    int
+	explain         bool
+	exportAll       bool
+	exportSQLite    string
+	fieldAliases    map[string]string
+	fields          []string
+	prefixMatch     bool
+	foldAccents     bool
+	format          string
+	full            bool
+	ignoreCode      bool
+	interval        time.Duration
+	jsonFlag        bool
+	jsonIndent      string
+	jqFlag          string
+	limit           int
+	limitPerCat     int
+	listCategories  bool
+	listPresets     bool
+	lucky           bool
+	matchReport     bool
+	max             int
+	maxMatches      int
+	maxOutputBytes  int
+	maxWords        int
+	memProfile      string
+	minAge          time.Duration
+	minWords        int
+	nameOnly        bool
+	needsTriage     bool
+	noBodyFetch     bool
+	openNewest      bool
+	openOldest      bool
+	outputDir       string
+	pinned          bool
+	preset          string
+	printURLs       bool
+	quiet           bool
+	render          bool
+	renderWidth     int
+	repoOverride    string
+	searchTerm      string
+	snippet         string
+	smart           bool
+	sortBy          string
+	sortGiven       bool
+	strictJSON      bool
+	unanswered      bool
+	tail            bool
+	tag             string
+	token           string
+	userAgent       string
+	weights         Weights
+	yes             bool
 }
 
 // Run the CLI
@@ -33,63 +112,554 @@ func runCLI() error {
 	// Parse flags
 	flags, err := parseFlags()
 	if err != nil {
-		return fmt.Errorf("failed to parse flags: %w", err)
+		return usageError{fmt.Errorf("failed to parse flags: %w", err)}
+	}
+
+	// Collect warnings and report them once after the results
+	var warnings Warnings
+	defer func() {
+		if !flags.quiet {
+			warnings.Print(os.Stderr)
+		}
+	}()
+
+	// Profile memory once everything else is done
+	if flags.memProfile != "" {
+		defer func() {
+			if err := writeMemProfile(flags.memProfile); err != nil {
+				warnings.Add("%v", err)
+			}
+		}()
+	}
+
+	// Cap the output size, cutting it at a line boundary
+	if flags.maxOutputBytes > 0 {
+		lw := &limitWriter{w: os.Stdout, limit: flags.maxOutputBytes}
+		stdout = lw
+		defer func() {
+			if err := lw.Flush(); err != nil {
+				warnings.Add("could not write output: %v", err)
+			}
+			if lw.truncated {
+				warnings.Add("output truncated to %d of at most %d bytes", lw.written, flags.maxOutputBytes)
+			}
+		}()
+	}
+
+	// List the output presets; no repository needed
+	if flags.listPresets {
+		return listPresets()
 	}
 
 	// Determine repository
 	repo, err := determineRepository(flags.repoOverride)
 	if err != nil {
-		return fmt.Errorf("could not determine repository: %w", err)
+		return usageError{fmt.Errorf("could not determine repository: %w", err)}
 	}
 
 	// Execute GraphQL query
-	gqlClient, err := gh.GQLClient(nil)
+	gqlClient, err := newGQLClient(repo.Host(), flags)
 	if err != nil {
 		return fmt.Errorf("could not create a GraphQL client: %w", err)
 	}
-	response, err := executeGraphQLQuery(gqlClient, constructGraphQLQuery(repo))
+
+	// List the repository's categories instead of searching
+	if flags.listCategories {
+		return listCategories(gqlClient, repo, flags)
+	}
+
+	// Guard against searches that would use up much of the rate limit
+	if err := confirmLargeQuery(flags); err != nil {
+		return err
+	}
+
+	// Compare against a second repository instead of listing matches
+	if flags.compare != "" {
+		other, err := repository.Parse(flags.compare)
+		if err != nil {
+			return usageError{fmt.Errorf("could not parse --compare repository: %w", err)}
+		}
+		return runCompare(gqlClient, repo, other, flags, &warnings)
+	}
+
+	// Stream new matches until interrupted
+	if flags.tail {
+		return runTail(gqlClient, repo, flags)
+	}
+
+	result, err := searchDiscussions(gqlClient, repo, flags, &warnings)
 	if err != nil {
-		return fmt.Errorf("failed to talk to the GitHub API: %w", err)
+		return err
+	}
+
+	matches := result.Matches
+	explanations := result.Explanations
+
+	// Broaden to word-prefix matching before giving up; a term without any
+	// words would match everything
+	hasWords := len(splitWords(normalizeText(flags.searchTerm, flags))) > 0
+	if len(matches) == 0 && flags.smart && hasWords {
+		// Everything downstream (comment trimming, highlighting, context)
+		// has to match the same way
+		flags.prefixMatch = true
+		matches = findMatchingDiscussions(result.Fetched, flags)
+		if len(matches) > 0 {
+			warnings.Add("no exact matches for '%s'; showing word-prefix matches instead", flags.searchTerm)
+			if flags.explain {
+				explanations = explainDiscussions(result.Fetched, flags)
+			}
+		}
 	}
 
-	// Handle discussions
-	if !response.Repository.HasDiscussionsEnabled {
-		return fmt.Errorf("%s/%s does not have discussions enabled", repo.Owner(), repo.Name())
+	// Show how every fetched discussion was scored instead of the matches
+	if flags.explain {
+		return outputExplanations(explanations, flags)
+	}
+	// Answer-based modes only consider answered discussions
+	if flags.answerOnly || flags.answersFeed {
+		matches = answeredDiscussions(matches)
+	}
+	if flags.maxMatches > 0 && flags.sortGiven {
+		warnings.Add("--max-matches stops scanning early, so --sort only orders the first %d matches found", flags.maxMatches)
+	}
+	sortDiscussions(matches, flags.sortBy)
+	if flags.collapseDups {
+		matches = collapseDuplicates(matches)
+	}
+	matches = limitDiscussions(matches, flags.limit, flags.limitPerCat)
+
+	// Write discussions to a SQLite database instead of printing them
+	if flags.exportSQLite != "" {
+		exported := matches
+		if flags.exportAll {
+			exported = result.Fetched
+		}
+		return exportSQLite(flags.exportSQLite, fmt.Sprintf("%s/%s", repo.Owner(), repo.Name()), exported)
+	}
+
+	// Summarize per-term hits after the results
+	if flags.matchReport && len(matches) > 0 {
+		defer printMatchReport(os.Stderr, matches, flags)
 	}
-	matches := findMatchingDiscussions(response, flags.searchTerm)
 
 	// No matches found
 	if len(matches) == 0 {
 		fmt.Fprintln(os.Stderr, "No matching discussion threads found :(")
+		if flags.exitCode {
+			return errNoMatches
+		}
 		return nil
 	}
 
-	// Open the first matching result in a web browser if lucky flag is set
+	// Open the first matching result in a web browser if lucky flag is set;
+	// matches are already sorted, by relevance unless --sort says otherwise
 	if flags.lucky {
-		b := browser.New("", os.Stdout, os.Stderr)
-		return b.Browse(matches[0].URL)
+		return openInBrowser(matches[0].URL, flags.printURLs)
+	}
+
+	// Open the newest or oldest matching result regardless of sort
+	if flags.openNewest || flags.openOldest {
+		return openInBrowser(pickByDate(matches, flags.openNewest).URL, flags.printURLs)
+	}
+
+	// Print accepted answers of answered matches
+	if flags.answerOnly && !flags.jsonFlag && flags.format == "" {
+		return outputAnswers(stdout, matches, flags)
+	}
+
+	// List recently chosen answers
+	if flags.answersFeed && !flags.jsonFlag && flags.format == "" {
+		return outputAnswersFeed(matches, flags)
+	}
+
+	// Export matches as issue template stubs
+	if flags.asIssueTemplate {
+		return exportIssueTemplates(matches, flags.outputDir)
+	}
+
+	// Print just the titles
+	if flags.nameOnly {
+		for _, d := range matches {
+			fmt.Fprintln(stdout, displayTitle(d.Title, flags))
+		}
+		return nil
+	}
+
+	// Print full bodies with the search term highlighted
+	if flags.full {
+		return outputFullBodies(stdout, matches, flags)
+	}
+
+	// Print matches with a built-in template
+	if flags.preset != "" {
+		return outputPreset(matches, flags.preset, flags)
+	}
+
+	// Summarize the matches in a sentence
+	if flags.digest {
+		return outputDigest(matches, flags)
+	}
+
+	// Refuse to emit JSON with fields the API left empty
+	if flags.strictJSON {
+		if err := validateDiscussions(matches, flags); err != nil {
+			return err
+		}
+	}
+
+	// Keep JSON payloads to the comments relevant to the search
+	if flags.commentsDepth > 0 && !flags.allComments {
+		matches = withMatchedComments(matches, flags)
+	}
+
+	// Wrap results with the search that produced them
+	if flags.format == "envelope" {
+		return handleJSONOutput(newEnvelope(matches, repo, flags), flags)
 	}
 
 	// Check if output is JSON
 	if flags.jsonFlag {
-		return handleJSONOutput(matches, flags.jqFlag)
+		return handleJSONOutput(jsonResults(matches, flags), flags)
 	}
 
 	// Output in table format
-	return outputInTableFormat(matches, repo, flags.searchTerm)
+	return outputInTableFormat(matches, repo, flags)
 }
 
 // Parse flags
-func parseFlags() (Flags, error) {
-	var flags Flags
+func parseFlags() (flags Flags, err error) {
+	flag.BoolVar(&flags.allComments, "all-comments", false, "Include every fetched comment in JSON, not just the ones that matched")
+	activeSince := flag.String("active-since", "", "Only match discussions with activity within this long, e.g. 36h or 7d")
+	flag.BoolVar(&flags.announcements, "announcements", false, "List discussions in the Announcements category, newest first")
+	flag.BoolVar(&flags.answerOnly, "answer-only", false, "Print only the accepted answers of answered matches")
+	flag.BoolVar(&flags.answerableOnly, "answerable-only", false, "Only match discussions in answerable categories such as Q&A, and show the category")
+	flag.BoolVar(&flags.answered, "answered", false, "Only match answered discussions in answerable categories such as Q&A")
+	flag.BoolVar(&flags.answersFeed, "answers-feed", false, "List answered matches with an answer excerpt, most recently answered first")
+	flag.BoolVar(&flags.asIssueTemplate, "as-issue-template", false, "Export matches as issue template Markdown stubs")
+	flag.StringVar(&flags.author, "author", "", "Only match discussions started by this user, by login or display name")
+	flag.StringVar(&flags.category, "category", "", "Only match discussions in this category")
+	flag.BoolVar(&flags.collapseDups, "collapse-duplicates", false, "Show matches with the same or very similar titles as one row")
+	flag.BoolVar(&flags.cleanTitles, "clean-titles", true, "Collapse whitespace and strip invisible characters in displayed titles")
+	flag.IntVar(&flags.commentsDepth, "comments-depth", 0, "Also search comments (1) or comments and their replies (2)")
+	flag.BoolVar(&flags.compactJSON, "compact-json", false, "Output JSON as a single compact array without pretty-printing")
+	flag.StringVar(&flags.compare, "compare", "", "Report matches found only in --repo or only in this other repository")
+	flag.IntVar(&flags.confirmLarge, "confirm-large-query", 0, "Ask before running a search estimated to cost more than this many rate limit points")
+	flag.IntVar(&flags.contextLines, "context", 0, "With --full, show only this many lines around each match instead of the whole body")
+	configPath := flag.String("config", "", "Read the --needs-triage policy from this file instead of gh-ask.yml in gh's config directory")
+	flag.StringVar(&flags.color, "color", "auto", "Use color in output: {always|never|auto}")
+	fieldAliases := flag.String("field-alias", "", "Rename table columns and add a header row, e.g. createdAt=Date,url=Link")
+	fields := flag.String("fields", "title,url", "Comma-separated table columns: "+strings.Join(fieldNames(), ", "))
+	flag.BoolVar(&flags.foldAccents, "fold-accents", false, "Ignore accents and diacritics when matching")
+	flag.BoolVar(&flags.digest, "digest", false, "Print a one-sentence summary of the matches instead of listing them")
+	flag.BoolVar(&flags.exitCode, "exit-code", false, "Exit with status 1 when nothing matches")
+	flag.BoolVar(&flags.explain, "explain", false, "Show the match decision and score of every fetched discussion")
+	flag.BoolVar(&flags.exportAll, "export-all", false, "With --export-sqlite, export every fetched discussion rather than only matches")
+	flag.StringVar(&flags.exportSQLite, "export-sqlite", "", "Write matches to the discussions table of the SQLite database at `path`")
+	flag.StringVar(&flags.format, "format", "", "Output format: {envelope}")
+	flag.BoolVar(&flags.full, "full", false, "Print the full body of each match")
+	flag.DurationVar(&flags.interval, "interval", time.Minute, "With --tail, how often to poll for new matches, e.g. 30s or 5m")
 	flag.BoolVar(&flags.jsonFlag, "json", false, "Output JSON")
-	flag.StringVar(&flags.jqFlag, "jq", "", "Process JSON output with a jq expression")
-	flag.BoolVar(&flags.lucky, "lucky", false, "Open the first matching result in a web browser")
+	jsonIndent := flag.String("json-indent", "1", "Indent pretty-printed JSON by this many spaces (0-8) or with tabs: {N|tab}")
+	flag.BoolVar(&flags.ignoreCode, "ignore-code", false, "Ignore code blocks in bodies when matching and building snippets")
+	flag.StringVar(&flags.jqFlag, "jq", "", "Process JSON output with a jq expression; implies --json")
+	first := flag.Bool("first", false, "Stop at the first match; same as --max-matches 1")
+	flag.IntVar(&flags.limit, "limit", 0, "Show at most this many matches")
+	flag.IntVar(&flags.limitPerCat, "limit-per-category", 0, "Show at most this many matches from each category")
+	flag.BoolVar(&flags.listCategories, "list-categories", false, "List the repository's discussion categories and exit")
+	flag.BoolVar(&flags.listPresets, "list-presets", false, "List the output presets available to --preset and exit")
+	flag.BoolVar(&flags.lucky, "lucky", false, "Open the best matching result in a web browser, by --sort or else relevance")
+	flag.BoolVar(&flags.matchReport, "match-report", false, "Report how often each word of the search term matched")
+	flag.IntVar(&flags.max, "max", 100, "Maximum number of discussions to fetch")
+	flag.IntVar(&flags.maxMatches, "max-matches", 0, "Stop fetching and matching once this many matches are found")
+	flag.IntVar(&flags.maxOutputBytes, "max-output-bytes", 0, "Stop writing output at the last whole line, or JSON record, within this many bytes")
+	flag.IntVar(&flags.maxWords, "max-words", 0, "Only match discussions whose body has at most this many words")
+	flag.StringVar(&flags.memProfile, "mem-profile", "", "Write a pprof heap profile to `path` after the run")
+	minAge := flag.String("min-age", "", "Only match discussions created at least this long ago, e.g. 12h or 2w")
+	flag.IntVar(&flags.minWords, "min-words", 0, "Only match discussions whose body has at least this many words")
+	flag.BoolVar(&flags.nameOnly, "name-only", false, "Print only the titles of matches, one per line")
+	flag.BoolVar(&flags.needsTriage, "needs-triage", false, "Only match discussions meeting the triage policy in the config file")
+	flag.BoolVar(&flags.noBodyFetch, "no-body-fetch", false, "Fetch only titles, URLs, and numbers and match titles only, for the fastest search")
+	flag.BoolVar(&flags.openNewest, "open-newest", false, "Open the newest matching result in a web browser")
+	flag.BoolVar(&flags.openOldest, "open-oldest", false, "Open the oldest matching result in a web browser")
+	flag.StringVar(&flags.outputDir, "output-dir", "", "With --as-issue-template, write one file per match to this directory")
+	flag.BoolVar(&flags.pinned, "pinned", false, "Only match pinned discussions")
+	flag.StringVar(&flags.preset, "preset", "", "Print matches with a built-in output template: {"+strings.Join(presetNames(), "|")+"}")
+	flag.BoolVar(&flags.printURLs, "print-urls-instead", false, "Print the URL an open action would launch instead of opening a browser")
+	flag.BoolVar(&flags.quiet, "quiet", false, "Do not print warnings")
+	flag.BoolVar(&flags.render, "render", false, "With --full or --answer-only, render bodies as Markdown")
+	flag.IntVar(&flags.renderWidth, "render-width", 0, "Wrap rendered Markdown at this width. If omitted, uses the terminal width")
 	flag.StringVar(&flags.repoOverride, "repo", "", "Specify a repository. If omitted, uses current repository")
+	flag.BoolVar(&flags.smart, "smart", true, "Retry with word-prefix matching when nothing matches exactly")
+	noSmart := flag.Bool("no-smart", false, "Disable the --smart word-prefix fallback")
+	flag.StringVar(&flags.snippet, "snippet", "", "Add a body snippet column: {lead|best}")
+	flag.StringVar(&flags.sortBy, "sort", "", "Sort matches: {newest|oldest|relevance|participation|answered}")
+	flag.BoolVar(&flags.strictJSON, "strict-json", false, "Fail instead of emitting JSON with fields missing from the API response")
+	flag.BoolVar(&flags.tail, "tail", false, "After listing matches, keep polling and print new matches as they appear")
+	flag.StringVar(&flags.tag, "tag", "", "Only match discussions with this label or in this category")
+	flag.StringVar(&flags.token, "token", "", "Authentication token, e.g. a GitHub App installation token. If omitted, uses gh's credentials")
+	flag.BoolVar(&flags.unanswered, "unanswered", false, "Only match unanswered discussions in answerable categories such as Q&A")
+	flag.StringVar(&flags.userAgent, "user-agent", "gh-ask/"+version(), "User-Agent header to send to the GitHub API")
+	weights := flag.String("weights", "", "Relevance weight per field, e.g. title=3,body=1,comments=0.5")
+	flag.BoolVar(&flags.yes, "yes", false, "Run searches over the --confirm-large-query threshold without asking")
 	flag.Parse()
 
-	// Ensure search term provided
-	if len(flag.Args()) < 1 {
+	switch flags.sortBy {
+	case "", "newest", "oldest", "relevance", "participation", "answered":
+	default:
+		return flags, fmt.Errorf("invalid sort %q: must be one of newest, oldest, relevance, participation, answered", flags.sortBy)
+	}
+	// Modes below pick a sort of their own when --sort is not given
+	flags.sortGiven = flags.sortBy != ""
+	if flags.answersFeed && flags.sortBy == "" {
+		flags.sortBy = "answered"
+	}
+	// Feeling lucky means opening the best match, not whichever came first
+	if flags.lucky && flags.sortBy == "" {
+		flags.sortBy = "relevance"
+	}
+
+	switch flags.color {
+	case "always", "never", "auto":
+	default:
+		return flags, fmt.Errorf("invalid color %q: must be one of always, never, auto", flags.color)
+	}
+
+	switch flags.format {
+	case "", "envelope":
+	default:
+		return flags, fmt.Errorf("invalid format %q: must be envelope", flags.format)
+	}
+
+	if flags.commentsDepth < 0 || flags.commentsDepth > 2 {
+		return flags, errors.New("--comments-depth must be 0, 1, or 2")
+	}
+
+	if flags.max < 1 {
+		return flags, errors.New("--max must be at least 1")
+	}
+	if flags.maxMatches < 0 {
+		return flags, errors.New("--max-matches cannot be negative")
+	}
+	if flags.interval < minTailInterval {
+		return flags, fmt.Errorf("--interval must be at least %s", minTailInterval)
+	}
+	if flags.answered && flags.unanswered {
+		return flags, errors.New("--answered and --unanswered cannot be used together")
+	}
+	if flags.confirmLarge < 0 {
+		return flags, errors.New("--confirm-large-query cannot be negative")
+	}
+	if flags.maxOutputBytes < 0 {
+		return flags, errors.New("--max-output-bytes cannot be negative")
+	}
+	if flags.limit < 0 || flags.limitPerCat < 0 {
+		return flags, errors.New("--limit and --limit-per-category cannot be negative")
+	}
+	if flags.minWords < 0 || flags.maxWords < 0 {
+		return flags, errors.New("--min-words and --max-words cannot be negative")
+	}
+	if *first {
+		flags.maxMatches = 1
+	}
+	if *noSmart {
+		flags.smart = false
+	}
+
+	if *activeSince != "" {
+		flags.activeSince, err = parseDuration(*activeSince)
+		if err != nil {
+			return flags, fmt.Errorf("invalid --active-since: %w", err)
+		}
+	}
+
+	if *minAge != "" {
+		flags.minAge, err = parseDuration(*minAge)
+		if err != nil {
+			return flags, fmt.Errorf("invalid --min-age: %w", err)
+		}
+	}
+
+	if _, ok := presets[flags.preset]; flags.preset != "" && !ok {
+		return flags, fmt.Errorf("invalid preset %q: must be one of %s", flags.preset, strings.Join(presetNames(), ", "))
+	}
+
+	flags.jsonIndent, err = parseJSONIndent(*jsonIndent)
+	if err != nil {
+		return flags, err
+	}
+
+	flags.fields, err = parseFields(*fields)
+	if err != nil {
+		return flags, err
+	}
+	flags.fieldAliases, err = parseFieldAliases(*fieldAliases)
+	if err != nil {
+		return flags, err
+	}
+
+	if flags.answerableOnly && !hasField(flags.fields, "category") {
+		flags.fields = append(flags.fields, "category")
+	}
+
+	switch flags.snippet {
+	case "":
+	case "lead", "best":
+		if !hasField(flags.fields, "snippet") {
+			flags.fields = append(flags.fields, "snippet")
+		}
+	default:
+		return flags, fmt.Errorf("invalid snippet %q: must be one of lead, best", flags.snippet)
+	}
+
+	// Only --needs-triage uses the config file, so nothing else can be
+	// broken by it
+	if flags.needsTriage {
+		flags.config, err = readConfig(*configPath)
+		if err != nil {
+			return flags, err
+		}
+	}
+
+	flags.weights, err = parseWeights(*weights)
+	if err != nil {
+		return flags, err
+	}
+
+	// Only one open action may be requested
+	opens := 0
+	for _, set := range []bool{flags.lucky, flags.openNewest, flags.openOldest} {
+		if set {
+			opens++
+		}
+	}
+	if opens > 1 {
+		return flags, errors.New("only one of --lucky, --open-newest, and --open-oldest may be used")
+	}
+	if flags.printURLs && opens == 0 {
+		return flags, errors.New("--print-urls-instead requires --lucky, --open-newest, or --open-oldest")
+	}
+	if flags.compactJSON || flags.jqFlag != "" {
+		flags.jsonFlag = true
+	}
+	// These have their own JSON shape and never wrap it in an envelope
+	for _, mode := range []struct {
+		name string
+		set  bool
+	}{
+		{"--compare", flags.compare != ""},
+		{"--digest", flags.digest},
+		{"--explain", flags.explain},
+		{"--list-categories", flags.listCategories},
+		{"--tail", flags.tail},
+	} {
+		if mode.set && flags.format != "" {
+			return flags, fmt.Errorf("%s cannot be combined with --format; use --json instead", mode.name)
+		}
+	}
+	// These print text before JSON output is reached, so --json and --jq
+	// would be silently ignored
+	for _, mode := range []struct {
+		name string
+		set  bool
+	}{
+		{"--as-issue-template", flags.asIssueTemplate},
+		{"--full", flags.full},
+		{"--name-only", flags.nameOnly},
+		{"--preset", flags.preset != ""},
+	} {
+		if mode.set && (flags.jsonFlag || flags.format != "") {
+			return flags, fmt.Errorf("%s prints text, so it cannot be combined with --json, --jq, or --format", mode.name)
+		}
+	}
+	// Aliases rename headers, which only the table and some presets have
+	if len(flags.fieldAliases) > 0 {
+		if flags.jsonFlag || flags.format != "" {
+			return flags, errors.New("--field-alias renames column headers, so it cannot be combined with --json, --jq, or --format")
+		}
+		if flags.preset != "" && !presets[flags.preset].Headers {
+			return flags, fmt.Errorf("--field-alias cannot be used with --preset %s, which has no header row", flags.preset)
+		}
+	}
+	if flags.strictJSON && !flags.jsonFlag && flags.format != "envelope" {
+		return flags, errors.New("--strict-json requires --json or --format envelope")
+	}
+	// A lean query fetches only titles, URLs, and numbers, so nothing that
+	// needs other fields can work
+	if flags.noBodyFetch {
+		for _, need := range []struct {
+			name string
+			set  bool
+		}{
+			{"--active-since", flags.activeSince > 0},
+			{"--announcements", flags.announcements},
+			{"--answer-only", flags.answerOnly},
+			{"--answerable-only", flags.answerableOnly},
+			{"--answered", flags.answered},
+			{"--answers-feed", flags.answersFeed},
+			{"--as-issue-template", flags.asIssueTemplate},
+			{"--author", flags.author != ""},
+			{"--category", flags.category != ""},
+			{"--comments-depth", flags.commentsDepth > 0},
+			{"--compare", flags.compare != ""},
+			{"--digest", flags.digest},
+			{"--export-sqlite", flags.exportSQLite != ""},
+			{"--full", flags.full},
+			{"--limit-per-category", flags.limitPerCat > 0},
+			{"--max-words", flags.maxWords > 0},
+			{"--min-age", flags.minAge > 0},
+			{"--min-words", flags.minWords > 0},
+			{"--needs-triage", flags.needsTriage},
+			{"--open-newest", flags.openNewest},
+			{"--open-oldest", flags.openOldest},
+			{"--preset", flags.preset != ""},
+			{"--sort " + flags.sortBy, flags.sortBy != "" && flags.sortBy != "relevance"},
+			{"--strict-json", flags.strictJSON},
+			{"--tag", flags.tag != ""},
+			{"--tail", flags.tail},
+			{"--unanswered", flags.unanswered},
+		} {
+			if need.set {
+				return flags, fmt.Errorf("%s needs more than titles, so it cannot be combined with --no-body-fetch", need.name)
+			}
+		}
+		for _, name := range flags.fields {
+			if name != "title" && name != "url" && name != "number" && name != "score" && name != "matchLocation" {
+				return flags, fmt.Errorf("the %s field needs more than titles, so it cannot be shown with --no-body-fetch", name)
+			}
+		}
+	}
+	if flags.contextLines < 0 {
+		return flags, errors.New("--context cannot be negative")
+	}
+	if flags.contextLines > 0 && (!flags.full || flags.render) {
+		return flags, errors.New("--context requires --full and cannot be combined with --render")
+	}
+	if flags.render && !flags.full && !flags.answerOnly {
+		return flags, errors.New("--render requires --full or --answer-only")
+	}
+	if flags.renderWidth < 0 {
+		return flags, errors.New("--render-width cannot be negative")
+	}
+	if flags.exportAll && flags.exportSQLite == "" {
+		return flags, errors.New("--export-all requires --export-sqlite")
+	}
+	if flags.outputDir != "" && !flags.asIssueTemplate {
+		return flags, errors.New("--output-dir requires --as-issue-template")
+	}
+
+	// Announcements mode is a shortcut for category + date sort
+	if flags.announcements {
+		if flags.category == "" {
+			flags.category = "Announcements"
+		}
+		if flags.sortBy == "" {
+			flags.sortBy = "newest"
+		}
+	}
+
+	// Ensure search term provided; announcements, pinned discussions,
+	// discussions needing triage, and categories may be listed without one
+	if len(flag.Args()) < 1 && !flags.announcements && !flags.pinned && !flags.needsTriage && !flags.listCategories {
 		return flags, errors.New("search term required")
 	}
 	flags.searchTerm = strings.Join(flag.Args(), " ")
@@ -97,6 +667,26 @@ func parseFlags() (Flags, error) {
 	return flags, nil
 }
 
+// Version of gh-ask according to the build info; "dev" for local builds
+func version() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok || info.Main.Version == "" || info.Main.Version == "(devel)" {
+		return "dev"
+	}
+	return info.Main.Version
+}
+
+// Create a GraphQL client for the repository's host that identifies itself
+// with the configured User-Agent. Without --token, gh resolves a token for
+// the host from GH_TOKEN, GH_ENTERPRISE_TOKEN, and its own configuration.
+func newGQLClient(host string, flags Flags) (api.GQLClient, error) {
+	return gh.GQLClient(&api.ClientOptions{
+		AuthToken: flags.token,
+		Headers:   map[string]string{"User-Agent": flags.userAgent},
+		Host:      host,
+	})
+}
+
 // Determine repository
 func determineRepository(repoOverride string) (repository.Repository, error) {
 	if repoOverride == "" {
@@ -105,94 +695,1011 @@ func determineRepository(repoOverride string) (repository.Repository, error) {
 	return repository.Parse(repoOverride)
 }
 
-// Execute GraphQL query
-func executeGraphQLQuery(client api.GQLClient, query string) (response struct {
+// SearchResult holds the outcome of searching one repository
+type SearchResult struct {
+	Matches []Discussion
+	// Explanations covers every fetched discussion, and is only filled in
+	// with --explain
+	Explanations []Explanation
+	// Fetched is every fetched discussion, kept for the --smart fallback
+	// and --export-all
+	Fetched []Discussion
+	// Truncated is set when the fetch limit stopped pagination early
+	Truncated bool
+}
+
+// Fetch discussions page by page and match them as they arrive, stopping at
+// the fetch limit or once enough matches have been collected
+func searchDiscussions(client api.GQLClient, repo repository.Repository, flags Flags, warnings *Warnings) (SearchResult, error) {
+	var result SearchResult
+	matches := []Discussion{}
+	sawCategory, sawAnswerable := false, false
+	pinned := map[int]bool{}
+	seen := map[int]bool{}
+	cursor := ""
+	for fetched := 0; fetched < flags.max; {
+		pageSize := flags.max - fetched
+		if pageSize > 100 {
+			pageSize = 100
+		}
+		response, err := executeGraphQLQuery(client, constructGraphQLQuery(repo, flags, pageSize, cursor))
+		if errors.Is(err, errRepoNotFound) || errors.Is(err, errNoPermission) {
+			return result, fmt.Errorf("%s/%s: %w", repo.Owner(), repo.Name(), err)
+		}
+		var gqlErr api.GQLError
+		if errors.As(err, &gqlErr) && response.Repository.HasDiscussionsEnabled {
+			// Partial data came back; search what we have
+			for _, e := range gqlErr.Errors {
+				warnings.Add("incomplete results from the GitHub API: %s", e.Message)
+			}
+		} else if err != nil {
+			return result, fmt.Errorf("failed to talk to the GitHub API: %w", err)
+		}
+		if !response.Repository.HasDiscussionsEnabled {
+			return result, fmt.Errorf("%s/%s %w", repo.Owner(), repo.Name(), errDiscussionsDisabled)
+		}
+
+		discussions := response.Repository.Discussions
+		fetched += len(discussions.Edges)
+		for _, p := range response.Repository.PinnedDiscussions.Nodes {
+			pinned[p.Discussion.Number] = true
+		}
+		// Pages can overlap when discussions change mid-pagination, so drop
+		// any discussion already seen
+		page := []Discussion{}
+		for _, d := range response.Nodes() {
+			if seen[d.Number] {
+				continue
+			}
+			seen[d.Number] = true
+			d.Pinned = pinned[d.Number]
+			page = append(page, d)
+		}
+		if flags.category != "" && hasCategory(page, flags.category) {
+			sawCategory = true
+		}
+		if hasAnswerable(page, flags.category) {
+			sawAnswerable = true
+		}
+		if flags.explain {
+			result.Explanations = append(result.Explanations, explainDiscussions(page, flags)...)
+		}
+		matches = append(matches, findMatchingDiscussions(page, flags)...)
+		// Kept after matching so --export-all rows carry scores too
+		if flags.smart || flags.exportAll {
+			result.Fetched = append(result.Fetched, page...)
+		}
+		if flags.maxMatches > 0 && len(matches) >= flags.maxMatches {
+			matches = matches[:flags.maxMatches]
+			break
+		}
+		if !discussions.PageInfo.HasNextPage || len(discussions.Edges) == 0 {
+			break
+		}
+		cursor = discussions.PageInfo.EndCursor
+		if fetched >= flags.max {
+			result.Truncated = true
+			warnings.Add("only the first %d discussions in %s/%s were searched; results may be incomplete, raise --max to search more",
+				fetched, repo.Owner(), repo.Name())
+		}
+	}
+
+	if flags.category != "" && !sawCategory {
+		warnings.Add("no fetched discussions are in the '%s' category", flags.category)
+	}
+	// Filters limited to answerable categories
+	filter := ""
+	switch {
+	case flags.answered:
+		filter = "--answered"
+	case flags.unanswered:
+		filter = "--unanswered"
+	case flags.answerableOnly:
+		filter = "--answerable-only"
+	}
+	if filter != "" && !sawAnswerable && len(seen) > 0 {
+		if sawCategory {
+			warnings.Add("the '%s' category is not answerable, so %s matches nothing in it", flags.category, filter)
+		} else if flags.category == "" {
+			warnings.Add("no fetched discussions are in an answerable category, so %s matches nothing", filter)
+		}
+	}
+	result.Matches = matches
+	return result, nil
+}
+
+// QueryResponse is the shape of the discussions query result
+type QueryResponse struct {
 	Repository struct {
 		Discussions struct {
 			Edges []struct {
 				Node Discussion
 			}
+			PageInfo struct {
+				HasNextPage bool
+				EndCursor   string
+			}
 		}
 		HasDiscussionsEnabled bool
+		// Only requested with the first page
+		PinnedDiscussions struct {
+			Nodes []struct {
+				Discussion struct {
+					Number int
+				}
+			}
+		}
 	}
-}, err error) {
+}
+
+// Nodes returns the discussions on this page of the response
+func (r QueryResponse) Nodes() []Discussion {
+	discussions := make([]Discussion, 0, len(r.Repository.Discussions.Edges))
+	for _, edge := range r.Repository.Discussions.Edges {
+		discussions = append(discussions, edge.Node)
+	}
+	return discussions
+}
+
+var (
+	// errDiscussionsDisabled means the repository has discussions turned off
+	errDiscussionsDisabled = errors.New("does not have discussions enabled")
+	// errRepoNotFound means the repository does not exist or the token cannot see it
+	errRepoNotFound = errors.New("repository not found or not accessible")
+	// errAuthFailed means the API rejected the token outright
+	errAuthFailed = errors.New("authentication failed: the token is invalid or has expired " +
+		"(GitHub App installation tokens expire after one hour)")
+	// errNoPermission means the token is valid but not allowed to read discussions
+	errNoPermission = errors.New("your token lacks permission to read discussions in this repository; " +
+		"ensure the Discussions read permission is granted")
+)
+
+// Execute GraphQL query
+func executeGraphQLQuery(client api.GQLClient, query string) (response QueryResponse, err error) {
 	err = client.Do(query, nil, &response)
-	return response, err
+	return response, mapGraphQLError(err)
 }
 
-// Find matching discussions
-func findMatchingDiscussions(response struct {
-	Repository struct {
-		Discussions           struct{ Edges []struct{ Node Discussion } }
-		HasDiscussionsEnabled bool
+// Replace well-known GraphQL errors with clearer ones
+func mapGraphQLError(err error) error {
+	var gqlErr api.GQLError
+	if errors.As(err, &gqlErr) && gqlErr.Match("NOT_FOUND", "repository") {
+		return errRepoNotFound
+	}
+	// Fine-grained tokens and GitHub Apps without the Discussions permission.
+	// Only errors on the repository or its discussions as a whole count;
+	// one on a nested field still leaves partial data to search.
+	if errors.As(err, &gqlErr) {
+		for _, e := range gqlErr.Errors {
+			forbidden := e.Type == "FORBIDDEN" || strings.Contains(e.Message, "Resource not accessible")
+			if forbidden && isRepositoryPath(e.Path) {
+				return errNoPermission
+			}
+		}
+	}
+	var httpErr api.HTTPError
+	if errors.As(err, &httpErr) && httpErr.StatusCode == 401 {
+		return errAuthFailed
+	}
+	return err
+}
+
+// Check whether a GraphQL error path is empty, the repository, or its
+// discussions connection
+func isRepositoryPath(path []interface{}) bool {
+	switch len(path) {
+	case 0:
+		return true
+	case 1:
+		return path[0] == "repository"
+	case 2:
+		return path[0] == "repository" && (path[1] == "discussions" || path[1] == "discussionCategories")
 	}
-}, search string) []Discussion {
+	return false
+}
+
+// Find matching discussions, scoring every discussion in place
+func findMatchingDiscussions(discussions []Discussion, flags Flags) []Discussion {
+	search := normalizeText(flags.searchTerm, flags)
 	matches := []Discussion{}
-	for _, edge := range response.Repository.Discussions.Edges {
-		if strings.Contains(edge.Node.Body+edge.Node.Title, search) {
-			matches = append(matches, edge.Node)
+	for i := range discussions {
+		if evaluateDiscussion(&discussions[i], search, flags) == "" {
+			matches = append(matches, discussions[i])
 		}
 	}
 	return matches
 }
 
-// Handle JSON output
-func handleJSONOutput(matches []Discussion, jqFlag string) error {
-	output, err := json.Marshal(matches)
+// Score a discussion and record where it matched; returns the reason it was
+// rejected, or an empty string if it matches
+func evaluateDiscussion(d *Discussion, search string, flags Flags) string {
+	d.MatchLocation = matchLocation(*d, search, flags)
+	d.Score = scoreDiscussion(*d, search, flags)
+	d.WordCount = len(strings.Fields(d.Body))
+	d.Participation = d.CommentCount.TotalCount + d.UpvoteCount + d.Reactions.TotalCount
+	if flags.matchReport {
+		d.TermHits = countTermHits(*d, search, flags)
+	}
+	if flags.category != "" && !strings.EqualFold(d.Category.Name, flags.category) {
+		return "category"
+	}
+	if flags.activeSince > 0 && time.Since(d.LastActive()) > flags.activeSince {
+		return "active-since"
+	}
+	if flags.minAge > 0 && time.Since(d.CreatedAt) < flags.minAge {
+		return "min-age"
+	}
+	if flags.minWords > 0 && d.WordCount < flags.minWords {
+		return "min-words"
+	}
+	if flags.maxWords > 0 && d.WordCount > flags.maxWords {
+		return "max-words"
+	}
+	if flags.author != "" && !d.Author.Is(flags.author) {
+		return "author"
+	}
+	if flags.tag != "" && !hasTag(*d, flags.tag) {
+		return "tag"
+	}
+	if (flags.answerableOnly || flags.answered || flags.unanswered) && !d.Category.IsAnswerable {
+		return "not answerable"
+	}
+	if flags.answered && !d.IsAnswered {
+		return "answered"
+	}
+	if flags.unanswered && d.IsAnswered {
+		return "unanswered"
+	}
+	if flags.pinned && !d.Pinned {
+		return "pinned"
+	}
+	if flags.needsTriage && !flags.config.Triage.Matches(*d) {
+		return "needs-triage"
+	}
+	if d.MatchLocation == "" {
+		return "no match"
+	}
+	return ""
+}
+
+// Report where the normalized search term first occurs: "title", "body",
+// "comment", or "reply"; empty when it does not occur at all
+func matchLocation(d Discussion, search string, flags Flags) string {
+	if containsMatch(d.Title, search, flags) {
+		return "title"
+	}
+	if containsMatch(proseText(d.Body, flags), search, flags) {
+		return "body"
+	}
+	return matchCommentLocation(d.Comments, search, flags)
+}
+
+// Check whether any fetched discussion belongs to a category
+func hasCategory(discussions []Discussion, category string) bool {
+	for _, d := range discussions {
+		if strings.EqualFold(d.Category.Name, category) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check whether a discussion has a label or category named tag, ignoring case
+func hasTag(d Discussion, tag string) bool {
+	return strings.EqualFold(d.Category.Name, tag) || hasAnyLabel(d, []string{tag})
+}
+
+// Check whether any fetched discussion is in an answerable category, or in
+// category when one is given
+func hasAnswerable(discussions []Discussion, category string) bool {
+	for _, d := range discussions {
+		if d.Category.IsAnswerable && (category == "" || strings.EqualFold(d.Category.Name, category)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Sort discussions in place; an empty sort keeps the API order.
+// Ties on the sort key are broken by URL, so that the same matches always come
+// out in the same order and open actions always pick the same discussion
+func sortDiscussions(discussions []Discussion, sortBy string) {
+	var compare func(a, b Discussion) int
+	switch sortBy {
+	case "newest":
+		compare = func(a, b Discussion) int { return compareTimes(b.CreatedAt, a.CreatedAt) }
+	case "oldest":
+		compare = func(a, b Discussion) int { return compareTimes(a.CreatedAt, b.CreatedAt) }
+	case "relevance":
+		compare = func(a, b Discussion) int { return compareFloats(b.Score, a.Score) }
+	case "participation":
+		compare = func(a, b Discussion) int { return compareFloats(float64(b.Participation), float64(a.Participation)) }
+	case "answered":
+		compare = func(a, b Discussion) int { return compareTimes(answerTime(b), answerTime(a)) }
+	default:
+		return
+	}
+	sort.SliceStable(discussions, func(i, j int) bool {
+		if c := compare(discussions[i], discussions[j]); c != 0 {
+			return c < 0
+		}
+		return discussions[i].URL < discussions[j].URL
+	})
+}
+
+// Compare two times, returning -1, 0, or 1
+func compareTimes(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
+
+// Compare two numbers, returning -1, 0, or 1
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Truncate sorted matches, first to perCategory per category and then to
+// limit overall; zero means no limit
+func limitDiscussions(discussions []Discussion, limit, perCategory int) []Discussion {
+	if perCategory > 0 {
+		counts := map[string]int{}
+		kept := []Discussion{}
+		for _, d := range discussions {
+			if counts[d.Category.Name] < perCategory {
+				counts[d.Category.Name]++
+				kept = append(kept, d)
+			}
+		}
+		discussions = kept
+	}
+	if limit > 0 && len(discussions) > limit {
+		discussions = discussions[:limit]
+	}
+	return discussions
+}
+
+// Pick the newest (or oldest) discussion by creation time, breaking ties by
+// URL like sortDiscussions
+func pickByDate(discussions []Discussion, newest bool) Discussion {
+	picked := discussions[0]
+	for _, d := range discussions[1:] {
+		c := compareTimes(d.CreatedAt, picked.CreatedAt)
+		if newest {
+			c = -c
+		}
+		if c < 0 || c == 0 && d.URL < picked.URL {
+			picked = d
+		}
+	}
+	return picked
+}
+
+// Open a URL in the user's web browser, or just print it
+func openInBrowser(url string, printOnly bool) error {
+	if printOnly {
+		fmt.Fprintln(stdout, url)
+		return nil
+	}
+	b := browser.New("", os.Stdout, os.Stderr)
+	return b.Browse(url)
+}
+
+// Weights holds the relevance weight of each searchable field
+type Weights struct {
+	Title    float64 `json:"title"`
+	Body     float64 `json:"body"`
+	Comments float64 `json:"comments"`
+}
+
+// Default relevance weights: a hit in the title counts double, a hit in a
+// comment or reply counts half
+var defaultWeights = Weights{Title: 2, Body: 1, Comments: 0.5}
+
+// Parse weights such as "title=3,body=1"; fields left out keep their default
+func parseWeights(s string) (Weights, error) {
+	weights := defaultWeights
+	if s == "" {
+		return weights, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		field, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return weights, fmt.Errorf("invalid weight %q: expected FIELD=NUMBER", pair)
+		}
+		w, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || w < 0 {
+			return weights, fmt.Errorf("invalid weight %q: must be a non-negative number", pair)
+		}
+		switch strings.TrimSpace(field) {
+		case "title":
+			weights.Title = w
+		case "body":
+			weights.Body = w
+		case "comments":
+			weights.Comments = w
+		default:
+			return weights, fmt.Errorf("invalid weight %q: unknown field %q", pair, field)
+		}
+	}
+	return weights, nil
+}
+
+// Score a discussion as the sum over fields of the number of occurrences of
+// the (normalized) search term multiplied by that field's weight
+func scoreDiscussion(d Discussion, search string, flags Flags) float64 {
+	if search == "" {
+		return 0
+	}
+	title := countMatches(d.Title, search, flags)
+	body := countMatches(proseText(d.Body, flags), search, flags)
+	comments := countInComments(d.Comments, search, flags)
+	return float64(title)*flags.weights.Title + float64(body)*flags.weights.Body +
+		float64(comments)*flags.weights.Comments
+}
+
+// Normalize text before matching according to flags
+func normalizeText(s string, flags Flags) string {
+	if flags.foldAccents {
+		s = foldAccents(s)
+	}
+	return s
+}
+
+// Body text to match against, without code blocks when --ignore-code is set
+func proseText(body string, flags Flags) string {
+	if flags.ignoreCode {
+		return stripCodeBlocks(body)
+	}
+	return body
+}
+
+// Remove fenced and indented Markdown code blocks, keeping the line
+// structure of the remaining prose
+func stripCodeBlocks(body string) string {
+	lines := strings.Split(body, "\n")
+	kept := make([]string, 0, len(lines))
+	fence := ""
+	previousBlank, inIndented := true, false
+	for _, line := range lines {
+		trimmed := strings.TrimLeft(line, " ")
+		indent := len(line) - len(trimmed)
+
+		// Inside a fence, skip until the matching closing fence
+		if fence != "" {
+			run := len(trimmed) - len(strings.TrimLeft(trimmed, fence[:1]))
+			if indent < 4 && run >= len(fence) && strings.TrimSpace(trimmed[run:]) == "" {
+				fence = ""
+			}
+			continue
+		}
+		if indent < 4 && (strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")) {
+			run := len(trimmed) - len(strings.TrimLeft(trimmed, trimmed[:1]))
+			fence = trimmed[:run]
+			continue
+		}
+
+		// Indented code starts after a blank line and runs until a
+		// non-blank line that is not indented
+		blank := strings.TrimSpace(line) == ""
+		indented := strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t")
+		if !blank && indented && (previousBlank || inIndented) {
+			inIndented = true
+			continue
+		}
+		if !blank {
+			inIndented = false
+		}
+		previousBlank = blank
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n")
+}
+
+// Check whether text contains the normalized search term. In prefix mode
+// every word of the term only has to start some word of the text.
+func containsMatch(text, search string, flags Flags) bool {
+	text = normalizeText(text, flags)
+	if !flags.prefixMatch {
+		return strings.Contains(text, search)
+	}
+	words := splitWords(text)
+	for _, token := range splitWords(search) {
+		if !hasWordWithPrefix(words, token) {
+			return false
+		}
+	}
+	return true
+}
+
+// Count occurrences of the normalized search term in text. In prefix mode
+// this is the number of words starting with any word of the term.
+func countMatches(text, search string, flags Flags) int {
+	text = normalizeText(text, flags)
+	if !flags.prefixMatch {
+		return strings.Count(text, search)
+	}
+	tokens := splitWords(search)
+	count := 0
+	for _, w := range splitWords(text) {
+		for _, token := range tokens {
+			if strings.HasPrefix(w, token) {
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
+
+// Split text into words of letters and digits
+func splitWords(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Check whether any of words starts with prefix
+func hasWordWithPrefix(words []string, prefix string) bool {
+	for _, w := range words {
+		if strings.HasPrefix(w, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Strip diacritics by decomposing to NFD and removing combining marks,
+// so that "café" and "cafe" compare equal
+func foldAccents(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(t, s)
+	if err != nil {
+		return s
+	}
+	return folded
+}
+
+// Envelope is self-describing JSON output recording the search behind the results
+type Envelope struct {
+	Repo       string          `json:"repo"`
+	Host       string          `json:"host"`
+	SearchTerm string          `json:"searchTerm"`
+	Filters    EnvelopeFilters `json:"filters"`
+	Count      int             `json:"count"`
+	Results    interface{}     `json:"results"`
+}
+
+// EnvelopeFilters records the flags that shaped the results
+type EnvelopeFilters struct {
+	Author           string  `json:"author,omitempty"`
+	Category         string  `json:"category,omitempty"`
+	Sort             string  `json:"sort,omitempty"`
+	Tag              string  `json:"tag,omitempty"`
+	Answered         bool    `json:"answered,omitempty"`
+	Unanswered       bool    `json:"unanswered,omitempty"`
+	AnswerableOnly   bool    `json:"answerableOnly,omitempty"`
+	Pinned           bool    `json:"pinned,omitempty"`
+	NeedsTriage      bool    `json:"needsTriage,omitempty"`
+	ActiveSince      string  `json:"activeSince,omitempty"`
+	MinAge           string  `json:"minAge,omitempty"`
+	MinWords         int     `json:"minWords,omitempty"`
+	MaxWords         int     `json:"maxWords,omitempty"`
+	CommentsDepth    int     `json:"commentsDepth,omitempty"`
+	IgnoreCode       bool    `json:"ignoreCode,omitempty"`
+	NoBodyFetch      bool    `json:"noBodyFetch,omitempty"`
+	FoldAccents      bool    `json:"foldAccents"`
+	PrefixMatch      bool    `json:"prefixMatch"`
+	Max              int     `json:"max"`
+	MaxMatches       int     `json:"maxMatches,omitempty"`
+	Limit            int     `json:"limit,omitempty"`
+	LimitPerCategory int     `json:"limitPerCategory,omitempty"`
+	Weights          Weights `json:"weights"`
+}
+
+// Format a duration filter the way --active-since and --min-age accept it;
+// empty when unset
+func durationFilter(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+// Build an envelope around the matches
+func newEnvelope(matches []Discussion, repo repository.Repository, flags Flags) Envelope {
+	return Envelope{
+		Repo:       fmt.Sprintf("%s/%s", repo.Owner(), repo.Name()),
+		Host:       repo.Host(),
+		SearchTerm: flags.searchTerm,
+		Filters: EnvelopeFilters{
+			Author:           flags.author,
+			Category:         flags.category,
+			Sort:             flags.sortBy,
+			Tag:              flags.tag,
+			Answered:         flags.answered,
+			Unanswered:       flags.unanswered,
+			AnswerableOnly:   flags.answerableOnly,
+			Pinned:           flags.pinned,
+			NeedsTriage:      flags.needsTriage,
+			ActiveSince:      durationFilter(flags.activeSince),
+			MinAge:           durationFilter(flags.minAge),
+			MinWords:         flags.minWords,
+			MaxWords:         flags.maxWords,
+			CommentsDepth:    flags.commentsDepth,
+			IgnoreCode:       flags.ignoreCode,
+			NoBodyFetch:      flags.noBodyFetch,
+			FoldAccents:      flags.foldAccents,
+			PrefixMatch:      flags.prefixMatch,
+			Max:              flags.max,
+			MaxMatches:       flags.maxMatches,
+			Limit:            flags.limit,
+			LimitPerCategory: flags.limitPerCat,
+			Weights:          flags.weights,
+		},
+		Count:   len(matches),
+		Results: jsonResults(matches, flags),
+	}
+}
+
+// TitleMatch is a match from a --no-body-fetch search, with only the fields
+// that were fetched
+type TitleMatch struct {
+	Number        int     `json:"number"`
+	Title         string  `json:"title"`
+	URL           string  `json:"url"`
+	Score         float64 `json:"score"`
+	MatchLocation string  `json:"matchLocation"`
+}
+
+// Matches as they should be serialized: whole discussions, or only the fetched
+// fields after a --no-body-fetch search
+func jsonResults(matches []Discussion, flags Flags) interface{} {
+	if !flags.noBodyFetch {
+		return matches
+	}
+	lean := make([]TitleMatch, len(matches))
+	for i, d := range matches {
+		lean[i] = TitleMatch{Number: d.Number, Title: d.Title, URL: d.URL, Score: d.Score, MatchLocation: d.MatchLocation}
+	}
+	return lean
+}
+
+// Title as it should be displayed; JSON output always keeps the raw title
+func displayTitle(title string, flags Flags) string {
+	if !flags.cleanTitles {
+		return title
+	}
+	return cleanTitle(title)
+}
+
+// Title of a discussion for display, marked when the discussion is pinned
+func discussionTitle(d Discussion, flags Flags) string {
+	if d.Pinned {
+		return "📌 " + displayTitle(d.Title, flags)
+	}
+	return displayTitle(d.Title, flags)
+}
+
+// Trim and collapse whitespace and drop zero-width and control characters
+func cleanTitle(title string) string {
+	cleaned := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return ' '
+		}
+		if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) {
+			return -1
+		}
+		return r
+	}, title)
+	return strings.Join(strings.Fields(cleaned), " ")
+}
+
+// Check that every discussion has the fields the query asked for
+func validateDiscussions(discussions []Discussion, flags Flags) error {
+	for _, d := range discussions {
+		missing := []string{}
+		if d.Number == 0 {
+			missing = append(missing, "number")
+		}
+		if d.Title == "" {
+			missing = append(missing, "title")
+		}
+		if d.URL == "" {
+			missing = append(missing, "url")
+		}
+		if d.CreatedAt.IsZero() {
+			missing = append(missing, "createdAt")
+		}
+		if d.Category.Name == "" {
+			missing = append(missing, "category")
+		}
+		if flags.commentsDepth > 0 && d.Comments == nil {
+			missing = append(missing, "comments")
+		}
+		if lastCommentQuery(flags) != "" && d.LastComment == nil {
+			missing = append(missing, "lastComment")
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("discussion %q is missing %s in the API response", d.URL, strings.Join(missing, ", "))
+		}
+	}
+	return nil
+}
+
+// Handle JSON output. Under --max-output-bytes the output is cut to whole
+// records rather than lines, so that it stays valid JSON.
+func handleJSONOutput(v interface{}, flags Flags) error {
+	if lw, ok := stdout.(*limitWriter); ok {
+		output, trimmed, err := fitJSON(v, lw.limit-lw.written, func(v interface{}) ([]byte, error) {
+			var buf bytes.Buffer
+			err := writeJSON(&buf, v, flags)
+			return buf.Bytes(), err
+		})
+		if err != nil {
+			return err
+		}
+		return lw.WriteRecord(output, trimmed)
+	}
+	return writeJSON(stdout, v, flags)
+}
+
+// Write v as JSON: compact, pretty-printed, or filtered through --jq
+func writeJSON(w io.Writer, v interface{}, flags Flags) error {
+	if flags.compactJSON && flags.jqFlag == "" {
+		if err := json.NewEncoder(w).Encode(v); err != nil {
+			return fmt.Errorf("could not serialize JSON: %w", err)
+		}
+		return nil
+	}
+	output, err := json.Marshal(v)
 	if err != nil {
 		return fmt.Errorf("could not serialize JSON: %w", err)
 	}
-	if jqFlag != "" {
-		return jq.Evaluate(bytes.NewBuffer(output), os.Stdout, jqFlag)
+	if flags.jqFlag != "" {
+		return jq.Evaluate(bytes.NewBuffer(output), w, flags.jqFlag)
 	}
 	isTerminal := term.IsTerminal(os.Stdout)
-	return jsonpretty.Format(os.Stdout, bytes.NewBuffer(output), " ", isTerminal)
+	return jsonpretty.Format(w, bytes.NewBuffer(output), flags.jsonIndent, isTerminal)
+}
+
+// Parse --json-indent into the indent string for one nesting level
+func parseJSONIndent(s string) (string, error) {
+	if s == "tab" {
+		return "\t", nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n > 8 {
+		return "", fmt.Errorf("invalid --json-indent %q: must be a number of spaces from 0 to 8, or tab", s)
+	}
+	return strings.Repeat(" ", n), nil
 }
 
 // Output in table format
-func outputInTableFormat(matches []Discussion, repo repository.Repository, search string) error {
+func outputInTableFormat(matches []Discussion, repo repository.Repository, flags Flags) error {
 	isTerminal := term.IsTerminal(os.Stdout)
-	tp := tableprinter.New(os.Stdout, isTerminal, 100)
+	tp := tableprinter.New(stdout, isTerminal, 100)
 
 	if isTerminal {
-		fmt.Printf(
-			"Searching discussions in '%s/%s' for '%s'\n",
-			repo.Owner(), repo.Name(), search)
+		if flags.searchTerm == "" {
+			fmt.Fprintf(stdout, "Listing discussions in '%s/%s'\n", repo.Owner(), repo.Name())
+		} else {
+			fmt.Fprintf(stdout,
+				"Searching discussions in '%s/%s' for '%s'\n",
+				repo.Owner(), repo.Name(), flags.searchTerm)
+		}
 	}
 
-	fmt.Println()
+	fmt.Fprintln(stdout)
+	if len(flags.fieldAliases) > 0 {
+		for _, name := range flags.fields {
+			tp.AddField(fieldHeader(name, flags.fieldAliases))
+		}
+		tp.EndRow()
+	}
+	now := time.Now()
 	for _, d := range matches {
-		tp.AddField(d.Title)
-		tp.AddField(d.URL)
+		for _, name := range flags.fields {
+			tp.AddField(tableFields[name](d, flags, now))
+		}
 		tp.EndRow()
 	}
 
 	return tp.Render()
 }
 
-// Construct GraphQL query
-func constructGraphQLQuery(repo repository.Repository) string {
+// Construct GraphQL query for one page of discussions, starting after cursor
+func constructGraphQLQuery(repo repository.Repository, flags Flags, first int, cursor string) string {
+	after := ""
+	pinned := "pinnedDiscussions(first: 10) { nodes { discussion { number } } }"
+	if cursor != "" {
+		after = fmt.Sprintf(", after: %q", cursor)
+		pinned = ""
+	}
+	fields := fmt.Sprintf(`number
+					title
+					body
+					url
+					createdAt
+					isAnswered
+					author { login ... on User { name } }
+					category { name emoji emojiHTML isAnswerable }
+					labels(first: 20) { nodes { name } }
+					upvoteCount
+					commentCount: comments { totalCount }
+					reactions { totalCount }
+					%s
+					%s
+					%s`, commentsQuery(flags.commentsDepth), lastCommentQuery(flags), answerQuery(flags))
+	// Titles are all a lean search needs
+	if flags.noBodyFetch {
+		fields = "number title url"
+	}
 	return fmt.Sprintf(`{
 		repository(owner: "%s", name: "%s") {
 			hasDiscussionsEnabled
-			discussions(first: 100) {
+			%s
+			discussions(first: %d%s) {
 				edges { node {
-					title
-					body
-					url
-	}}}}}`, repo.Owner(), repo.Name())
+					%s
+				}}
+				pageInfo { hasNextPage endCursor }
+	}}}`, repo.Owner(), repo.Name(), pinned, first, after, fields)
+}
+
+// Warnings collects non-fatal problems encountered during a run
+type Warnings []string
+
+// Add records a warning
+func (w *Warnings) Add(format string, args ...interface{}) {
+	*w = append(*w, fmt.Sprintf(format, args...))
+}
+
+// Print writes all collected warnings as a single section
+func (w Warnings) Print(out io.Writer) {
+	if len(w) == 0 {
+		return
+	}
+	fmt.Fprintln(out, "\nWarnings:")
+	for _, warning := range w {
+		fmt.Fprintf(out, "  - %s\n", warning)
+	}
 }
 
 // Discussion struct represents a discussion on GitHub
 type Discussion struct {
-	Title string
-	URL   string `json:"url"`
-	Body  string
+	Number         int `json:"number"`
+	Title          string
+	URL            string `json:"url"`
+	Body           string
+	CreatedAt      time.Time      `json:"createdAt"`
+	Author         Author         `json:"author"`
+	Category       Category       `json:"category"`
+	Labels         Labels         `json:"labels"`
+	Comments       *Comments      `json:"comments,omitempty"`
+	LastComment    *LastComment   `json:"lastComment,omitempty"`
+	Answer         *Answer        `json:"answer,omitempty"`
+	AnswerChosenAt *time.Time     `json:"answerChosenAt,omitempty"`
+	IsAnswered     bool           `json:"isAnswered"`
+	Pinned         bool           `json:"pinned"`
+	UpvoteCount    int            `json:"upvoteCount"`
+	CommentCount   Count          `json:"commentCount"`
+	Reactions      Count          `json:"reactions"`
+	Participation  int            `json:"participation"`
+	Score          float64        `json:"score"`
+	WordCount      int            `json:"wordCount"`
+	MatchLocation  string         `json:"matchLocation,omitempty"`
+	TermHits       map[string]int `json:"termHits,omitempty"`
+	DuplicateURLs  []string       `json:"duplicateUrls,omitempty"`
+}
+
+// LastActive is when the discussion last had a comment, or was created if
+// it has none or comment times were not fetched
+func (d Discussion) LastActive() time.Time {
+	if d.LastComment != nil && len(d.LastComment.Nodes) > 0 {
+		return d.LastComment.Nodes[0].CreatedAt
+	}
+	return d.CreatedAt
+}
+
+// Count holds the total size of a connection
+type Count struct {
+	TotalCount int `json:"totalCount"`
+}
+
+// Labels holds the labels applied to a discussion
+type Labels struct {
+	Nodes []Label `json:"nodes"`
+}
+
+// Label struct represents a label on GitHub
+type Label struct {
+	Name string `json:"name"`
+}
+
+// Author struct represents the user who started a discussion; Name is empty
+// for users without a display name and for non-user actors such as bots
+type Author struct {
+	Login string `json:"login"`
+	Name  string `json:"name,omitempty"`
+}
+
+// Is reports whether who is the author's login or display name, ignoring case
+func (a Author) Is(who string) bool {
+	return strings.EqualFold(a.Login, who) || (a.Name != "" && strings.EqualFold(a.Name, who))
+}
+
+// Label for the author: the display name if that is what --author matched,
+// otherwise the login
+func (a Author) Label(flags Flags) string {
+	if flags.author != "" && !strings.EqualFold(a.Login, flags.author) && strings.EqualFold(a.Name, flags.author) {
+		return a.Name
+	}
+	return a.Login
+}
+
+// Category struct represents a discussion category
+type Category struct {
+	Name         string `json:"name"`
+	Emoji        string `json:"emoji,omitempty"`
+	EmojiHTML    string `json:"emojiHTML,omitempty"`
+	IsAnswerable bool   `json:"isAnswerable"`
+}
+
+// Exit codes for each class of failure
+const (
+	exitNoMatches = 1
+	exitUsage     = 2
+	exitAPI       = 3
+	exitNotFound  = 4
+	exitOther     = 5
+)
+
+// errNoMatches is returned with --exit-code when nothing matched
+var errNoMatches = errors.New("no matches")
+
+// usageError marks errors caused by invalid command line usage
+type usageError struct {
+	err error
+}
+
+func (e usageError) Error() string { return e.err.Error() }
+func (e usageError) Unwrap() error { return e.err }
+
+// Map an error from runCLI to the process exit code
+func exitCodeFor(err error) int {
+	var usageErr usageError
+	var httpErr api.HTTPError
+	var gqlErr api.GQLError
+	var urlErr *url.Error
+	switch {
+	case errors.Is(err, errNoMatches):
+		return exitNoMatches
+	case errors.As(err, &usageErr):
+		return exitUsage
+	case errors.Is(err, errRepoNotFound), errors.Is(err, errDiscussionsDisabled):
+		return exitNotFound
+	case errors.Is(err, errAuthFailed), errors.Is(err, errNoPermission), errors.As(err, &httpErr), errors.As(err, &gqlErr), errors.As(err, &urlErr):
+		return exitAPI
+	}
+	return exitOther
 }
 
 func main() {
 	if err := runCLI(); err != nil {
-		fmt.Fprintf(os.Stderr, "gh-ask failed: %s\n", err.Error())
-		os.Exit(1)
+		if !errors.Is(err, errNoMatches) {
+			fmt.Fprintf(os.Stderr, "gh-ask failed: %s\n", err.Error())
+		}
+		os.Exit(exitCodeFor(err))
 	}
 }
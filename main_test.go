@@ -0,0 +1,439 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cli/go-gh/pkg/api"
+	"github.com/cli/go-gh/pkg/repository"
+)
+
+func TestFoldAccents(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"café", "cafe"},
+		{"naïve résumé", "naive resume"},
+		{"Ångström", "Angstrom"},
+		{"plain", "plain"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := foldAccents(tt.in); got != tt.want {
+			t.Errorf("foldAccents(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestContainsMatchAccents(t *testing.T) {
+	tests := []struct {
+		text   string
+		search string
+		fold   bool
+		want   bool
+	}{
+		{"Problème de café", "cafe", true, true},
+		{"Problème de café", "cafe", false, false},
+		{"Problème de cafe", "café", true, true},
+		{"Problème de café", "café", false, true},
+		{"CAFÉ crash", "CAFE", true, true},
+		{"tea", "cafe", true, false},
+	}
+	for _, tt := range tests {
+		flags := Flags{foldAccents: tt.fold}
+		search := normalizeText(tt.search, flags)
+		if got := containsMatch(tt.text, search, flags); got != tt.want {
+			t.Errorf("containsMatch(%q, %q) with fold=%v = %v, want %v", tt.text, tt.search, tt.fold, got, tt.want)
+		}
+	}
+}
+
+func TestCleanTitle(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Crash\u200b on start", "Crash on start"},
+		{"\ufeffBOM title", "BOM title"},
+		{"Tabs\tand\t\ttabs", "Tabs and tabs"},
+		{"  padded  \n title \r\n", "padded title"},
+		{"Bell\x07 and\x00 nul", "Bell and nul"},
+		{"Joiner\u200d\u2060 here", "Joiner here"},
+		{"Already clean", "Already clean"},
+	}
+	for _, tt := range tests {
+		if got := cleanTitle(tt.in); got != tt.want {
+			t.Errorf("cleanTitle(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestStripCodeBlocks(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "backtick fence",
+			in:   "before\n```go\npanic(\"timeout\")\n```\nafter",
+			want: "before\nafter",
+		},
+		{
+			name: "tilde fence",
+			in:   "before\n~~~\ntimeout\n~~~\nafter",
+			want: "before\nafter",
+		},
+		{
+			name: "longer closing fence",
+			in:   "before\n````\n```\ntimeout\n```\n`````\nafter",
+			want: "before\nafter",
+		},
+		{
+			name: "other fence character does not close",
+			in:   "before\n```\n~~~\ntimeout\n```\nafter",
+			want: "before\nafter",
+		},
+		{
+			name: "indented code after a blank line",
+			in:   "before\n\n    timeout()\n    retry()\nafter",
+			want: "before\n\nafter",
+		},
+		{
+			name: "tab-indented code",
+			in:   "before\n\n\ttimeout()\nafter",
+			want: "before\n\nafter",
+		},
+		{
+			name: "indented continuation of a paragraph is kept",
+			in:   "before\n    still prose",
+			want: "before\n    still prose",
+		},
+		{
+			name: "no code",
+			in:   "just prose",
+			want: "just prose",
+		},
+	}
+	for _, tt := range tests {
+		if got := stripCodeBlocks(tt.in); got != tt.want {
+			t.Errorf("%s: stripCodeBlocks(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestExitCodeFor(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"no matches", errNoMatches, exitNoMatches},
+		{"usage", usageError{errors.New("bad flag")}, exitUsage},
+		{"wrapped usage", fmt.Errorf("parsing: %w", usageError{errors.New("bad flag")}), exitUsage},
+		{"repository not found", fmt.Errorf("owner/repo: %w", errRepoNotFound), exitNotFound},
+		{"discussions disabled", fmt.Errorf("owner/repo %w", errDiscussionsDisabled), exitNotFound},
+		{"authentication failed", errAuthFailed, exitAPI},
+		{"no permission", fmt.Errorf("owner/repo: %w", errNoPermission), exitAPI},
+		{"GraphQL error", fmt.Errorf("failed to talk to the GitHub API: %w",
+			api.GQLError{Errors: []api.GQLErrorItem{{Message: "boom"}}}), exitAPI},
+		{"network error", &url.Error{Op: "Post", URL: "https://api.github.com/graphql", Err: errors.New("dial tcp")}, exitAPI},
+		{"other", errors.New("could not write SQLite database"), exitOther},
+	}
+	for _, tt := range tests {
+		if got := exitCodeFor(tt.err); got != tt.want {
+			t.Errorf("%s: exitCodeFor(%v) = %d, want %d", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
+
+// Run parseFlags on args with a fresh flag set
+func parseTestFlags(t *testing.T, args ...string) (Flags, error) {
+	t.Helper()
+	oldArgs, oldCommandLine := os.Args, flag.CommandLine
+	t.Cleanup(func() { os.Args, flag.CommandLine = oldArgs, oldCommandLine })
+	os.Args = append([]string{"gh-ask"}, args...)
+	flag.CommandLine = flag.NewFlagSet("gh-ask", flag.ContinueOnError)
+	flag.CommandLine.SetOutput(io.Discard)
+	return parseFlags()
+}
+
+func TestLuckyOpensHighestScore(t *testing.T) {
+	flags, err := parseTestFlags(t, "--lucky", "timeout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flags.sortBy != "relevance" {
+		t.Fatalf("--lucky sort = %q, want relevance", flags.sortBy)
+	}
+	if flags.sortGiven {
+		t.Error("--lucky should not count as an explicit --sort")
+	}
+
+	matches := []Discussion{
+		{URL: "https://github.com/o/r/discussions/1", Score: 1},
+		{URL: "https://github.com/o/r/discussions/2", Score: 4.5},
+		{URL: "https://github.com/o/r/discussions/3", Score: 2},
+	}
+	sortDiscussions(matches, flags.sortBy)
+	if got := matches[0].URL; got != "https://github.com/o/r/discussions/2" {
+		t.Errorf("--lucky would open %s, want the highest scoring discussion 2", got)
+	}
+}
+
+// GraphQL client that answers each query with the next canned page
+type fakeGQLClient struct {
+	api.GQLClient
+	pages []string
+	calls int
+}
+
+func (c *fakeGQLClient) Do(query string, variables map[string]interface{}, response interface{}) error {
+	page := c.pages[c.calls]
+	c.calls++
+	return json.Unmarshal([]byte(page), response)
+}
+
+// A page of discussions with the given numbers, all titled to match "timeout"
+func discussionsPage(hasNextPage bool, numbers ...int) string {
+	edges := []map[string]interface{}{}
+	for _, n := range numbers {
+		edges = append(edges, map[string]interface{}{"node": map[string]interface{}{
+			"number": n,
+			"title":  fmt.Sprintf("timeout %d", n),
+			"url":    fmt.Sprintf("https://github.com/o/r/discussions/%d", n),
+		}})
+	}
+	page, _ := json.Marshal(map[string]interface{}{"repository": map[string]interface{}{
+		"hasDiscussionsEnabled": true,
+		"discussions": map[string]interface{}{
+			"edges":    edges,
+			"pageInfo": map[string]interface{}{"hasNextPage": hasNextPage, "endCursor": "cursor"},
+		},
+	}})
+	return string(page)
+}
+
+func TestSearchDiscussionsDropsOverlappingPages(t *testing.T) {
+	client := &fakeGQLClient{pages: []string{
+		discussionsPage(true, 1, 2, 3),
+		discussionsPage(true, 3, 4),
+		discussionsPage(false, 2, 5),
+	}}
+	repo, err := repository.Parse("o/r")
+	if err != nil {
+		t.Fatal(err)
+	}
+	flags := Flags{searchTerm: "timeout", max: 100, weights: defaultWeights}
+
+	var warnings Warnings
+	result, err := searchDiscussions(client, repo, flags, &warnings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []int{}
+	for _, d := range result.Matches {
+		got = append(got, d.Number)
+	}
+	want := []int{1, 2, 3, 4, 5}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("matched discussions %v, want each once: %v", got, want)
+	}
+	if client.calls != 3 {
+		t.Errorf("fetched %d pages, want 3", client.calls)
+	}
+}
+
+func TestParseFlagsOutputCombinations(t *testing.T) {
+	tests := []struct {
+		args     []string
+		wantErr  bool
+		wantJSON bool
+	}{
+		{args: []string{"--jq", ".[0]", "q"}, wantJSON: true},
+		{args: []string{"--json", "q"}, wantJSON: true},
+		{args: []string{"--compact-json", "q"}, wantJSON: true},
+		{args: []string{"--format", "envelope", "--jq", ".count", "q"}, wantJSON: true},
+		{args: []string{"--explain", "--jq", ".", "q"}, wantJSON: true},
+		{args: []string{"--digest", "--jq", ".count", "q"}, wantJSON: true},
+		{args: []string{"q"}},
+		{args: []string{"--full", "q"}},
+		{args: []string{"--full", "--json", "q"}, wantErr: true},
+		{args: []string{"--full", "--jq", ".", "q"}, wantErr: true},
+		{args: []string{"--name-only", "--format", "envelope", "q"}, wantErr: true},
+		{args: []string{"--preset", "slack", "--json", "q"}, wantErr: true},
+		{args: []string{"--as-issue-template", "--jq", ".", "q"}, wantErr: true},
+		{args: []string{"--explain", "--format", "envelope", "q"}, wantErr: true},
+		{args: []string{"--digest", "--format", "envelope", "--jq", ".", "q"}, wantErr: true},
+		{args: []string{"--strict-json", "q"}, wantErr: true},
+		{args: []string{"--format", "yaml", "q"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		flags, err := parseTestFlags(t, tt.args...)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseFlags(%q) error = %v, want error: %v", tt.args, err, tt.wantErr)
+			continue
+		}
+		if err == nil && flags.jsonFlag != tt.wantJSON {
+			t.Errorf("parseFlags(%q) json = %v, want %v", tt.args, flags.jsonFlag, tt.wantJSON)
+		}
+	}
+}
+
+func TestSortBreaksTiesByURL(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tied := func() []Discussion {
+		return []Discussion{
+			{URL: "https://github.com/o/r/discussions/3", CreatedAt: created, Score: 1, Participation: 2},
+			{URL: "https://github.com/o/r/discussions/1", CreatedAt: created, Score: 1, Participation: 2},
+			{URL: "https://github.com/o/r/discussions/2", CreatedAt: created, Score: 1, Participation: 2},
+		}
+	}
+	want := "[https://github.com/o/r/discussions/1 https://github.com/o/r/discussions/2 https://github.com/o/r/discussions/3]"
+	for _, sortBy := range []string{"newest", "oldest", "relevance", "participation", "answered"} {
+		discussions := tied()
+		sortDiscussions(discussions, sortBy)
+		got := []string{}
+		for _, d := range discussions {
+			got = append(got, d.URL)
+		}
+		if fmt.Sprint(got) != want {
+			t.Errorf("--sort %s ordered ties as %v, want %s", sortBy, got, want)
+		}
+	}
+
+	// Without --sort the API order is kept
+	discussions := tied()
+	sortDiscussions(discussions, "")
+	if discussions[0].URL != "https://github.com/o/r/discussions/3" {
+		t.Errorf("no sort reordered discussions: first is %s", discussions[0].URL)
+	}
+}
+
+func TestPickByDateBreaksTiesByURL(t *testing.T) {
+	older := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+	discussions := []Discussion{
+		{URL: "https://github.com/o/r/discussions/9", CreatedAt: newer},
+		{URL: "https://github.com/o/r/discussions/4", CreatedAt: older},
+		{URL: "https://github.com/o/r/discussions/5", CreatedAt: newer},
+		{URL: "https://github.com/o/r/discussions/7", CreatedAt: older},
+	}
+	if got := pickByDate(discussions, true).URL; got != "https://github.com/o/r/discussions/5" {
+		t.Errorf("newest pick = %s, want discussions/5", got)
+	}
+	if got := pickByDate(discussions, false).URL; got != "https://github.com/o/r/discussions/4" {
+		t.Errorf("oldest pick = %s, want discussions/4", got)
+	}
+}
+
+// A page of 100 discussions as the default query returns them, or as the
+// --no-body-fetch query does
+func benchmarkPage(lean bool) []byte {
+	edges := []map[string]interface{}{}
+	for n := 1; n <= 100; n++ {
+		node := map[string]interface{}{
+			"number": n,
+			"title":  fmt.Sprintf("Request timeout when uploading large files %d", n),
+			"url":    fmt.Sprintf("https://github.com/o/r/discussions/%d", n),
+		}
+		if !lean {
+			node["body"] = strings.Repeat("Uploading a large file fails after thirty seconds. ", 40)
+			node["createdAt"] = "2024-05-01T12:00:00Z"
+			node["isAnswered"] = true
+			node["author"] = map[string]interface{}{"login": "octocat", "name": "The Octocat"}
+			node["category"] = map[string]interface{}{"name": "Q&A", "emoji": ":pray:", "emojiHTML": "<div>🙏</div>", "isAnswerable": true}
+			node["labels"] = map[string]interface{}{"nodes": []map[string]string{{"name": "bug"}, {"name": "uploads"}}}
+			node["upvoteCount"] = 3
+			node["commentCount"] = map[string]int{"totalCount": 4}
+			node["reactions"] = map[string]int{"totalCount": 2}
+		}
+		edges = append(edges, map[string]interface{}{"node": node})
+	}
+	page, _ := json.Marshal(map[string]interface{}{"repository": map[string]interface{}{
+		"hasDiscussionsEnabled": true,
+		"discussions": map[string]interface{}{
+			"edges":    edges,
+			"pageInfo": map[string]interface{}{"hasNextPage": false, "endCursor": "cursor"},
+		},
+	}})
+	return page
+}
+
+// Compare the payload size and decoding cost of a default page with a
+// --no-body-fetch page; the bytes/page metric shows the payload reduction
+func BenchmarkPagePayload(b *testing.B) {
+	for _, bench := range []struct {
+		name string
+		lean bool
+	}{{"default", false}, {"no-body-fetch", true}} {
+		page := benchmarkPage(bench.lean)
+		b.Run(bench.name, func(b *testing.B) {
+			b.SetBytes(int64(len(page)))
+			b.ReportMetric(float64(len(page)), "bytes/page")
+			for i := 0; i < b.N; i++ {
+				var response QueryResponse
+				if err := json.Unmarshal(page, &response); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchDiscussionsScoresFetched(t *testing.T) {
+	client := &fakeGQLClient{pages: []string{discussionsPage(false, 1, 2)}}
+	repo, err := repository.Parse("o/r")
+	if err != nil {
+		t.Fatal(err)
+	}
+	flags := Flags{searchTerm: "timeout", max: 100, exportAll: true, weights: defaultWeights}
+
+	var warnings Warnings
+	result, err := searchDiscussions(client, repo, flags, &warnings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range result.Fetched {
+		if d.Score == 0 || d.MatchLocation != "title" {
+			t.Errorf("fetched discussion %d has score %v and match location %q, want them set", d.Number, d.Score, d.MatchLocation)
+		}
+	}
+}
+
+func TestFieldAliasesRenamePresetHeaders(t *testing.T) {
+	flags, err := parseTestFlags(t, "--preset", "csv-report", "--field-alias", "createdAt=Date,url=Link", "q")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	oldStdout := stdout
+	t.Cleanup(func() { stdout = oldStdout })
+	stdout = &out
+	if err := outputPreset(nil, flags.preset, flags); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "number,title,category,author,Date,answered,Link\n"; got != want {
+		t.Errorf("csv-report header = %q, want %q", got, want)
+	}
+
+	for _, args := range [][]string{
+		{"--preset", "slack", "--field-alias", "url=Link", "q"},
+		{"--json", "--field-alias", "url=Link", "q"},
+		{"--field-alias", "nope=Link", "q"},
+	} {
+		if _, err := parseTestFlags(t, args...); err == nil {
+			t.Errorf("parseFlags(%q) succeeded, want an error", args)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"reflect"
+)
+
+// Writer for all result output; wrapped by --max-output-bytes
+var stdout io.Writer = os.Stdout
+
+// Writer that passes output through whole lines at a time and drops
+// everything from the first line that would exceed the limit
+type limitWriter struct {
+	w         io.Writer
+	limit     int
+	written   int
+	pending   []byte
+	truncated bool
+}
+
+func (lw *limitWriter) Write(p []byte) (int, error) {
+	if lw.truncated {
+		return len(p), nil
+	}
+	lw.pending = append(lw.pending, p...)
+	for {
+		i := bytes.IndexByte(lw.pending, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		if err := lw.emit(lw.pending[:i+1]); err != nil || lw.truncated {
+			return len(p), err
+		}
+		lw.pending = lw.pending[i+1:]
+	}
+}
+
+// Write a whole record, such as a JSON document, if it fits; trimmed marks
+// the output as truncated even if the record fits because it was cut down
+func (lw *limitWriter) WriteRecord(record []byte, trimmed bool) error {
+	if err := lw.Flush(); err != nil || lw.truncated {
+		return err
+	}
+	lw.pending = nil
+	err := lw.emit(record)
+	lw.truncated = lw.truncated || trimmed
+	return err
+}
+
+// Write out a trailing partial line if it still fits
+func (lw *limitWriter) Flush() error {
+	if lw.truncated || len(lw.pending) == 0 {
+		return nil
+	}
+	return lw.emit(lw.pending)
+}
+
+func (lw *limitWriter) emit(record []byte) error {
+	if lw.written+len(record) > lw.limit {
+		lw.truncated = true
+		lw.pending = nil
+		return nil
+	}
+	n, err := lw.w.Write(record)
+	lw.written += n
+	return err
+}
+
+// Render v with as many of its records as fit in limit bytes, reporting
+// whether any had to be dropped. Lists are cut to their first elements, and
+// an envelope to its first results; anything else is all or nothing.
+func fitJSON(v interface{}, limit int, render func(interface{}) ([]byte, error)) ([]byte, bool, error) {
+	output, err := render(v)
+	if err != nil || len(output) <= limit {
+		return output, false, err
+	}
+	count, cut := jsonRecords(v)
+	if cut == nil {
+		return output, false, nil
+	}
+
+	// Binary search for the most records that still fit
+	best, err := render(cut(0))
+	if err != nil {
+		return nil, false, err
+	}
+	for lo, hi := 1, count-1; lo <= hi; {
+		mid := (lo + hi) / 2
+		candidate, err := render(cut(mid))
+		if err != nil {
+			return nil, false, err
+		}
+		if len(candidate) <= limit {
+			best, lo = candidate, mid+1
+		} else {
+			hi = mid - 1
+		}
+	}
+	return best, true, nil
+}
+
+// Number of records in v and a function returning v cut down to its first n
+// records, or a nil function if v is not a list of records
+func jsonRecords(v interface{}) (int, func(n int) interface{}) {
+	if envelope, ok := v.(Envelope); ok {
+		count, cut := jsonRecords(envelope.Results)
+		if cut == nil {
+			return 0, nil
+		}
+		return count, func(n int) interface{} {
+			trimmed := envelope
+			trimmed.Results = cut(n)
+			trimmed.Count = n
+			return trimmed
+		}
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return 0, nil
+	}
+	return rv.Len(), func(n int) interface{} {
+		return rv.Slice(0, n).Interface()
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/cli/go-gh/pkg/tableprinter"
+	"github.com/cli/go-gh/pkg/term"
+)
+
+// Preset is a named output template for a common sharing target
+type Preset struct {
+	Description string
+	Template    string
+	// Headers is set for presets with a header row, which --field-alias
+	// renames through the header function
+	Headers bool
+}
+
+// presets are the output templates that can be selected with --preset; each
+// is executed with the list of matches
+var presets = map[string]Preset{
+	"slack": {
+		Description: "Bulleted list of linked titles to paste into Slack",
+		Template: `{{range .}}• <{{.URL}}|{{slack .Title}}> ({{.Category.Name}})
+{{end}}`,
+	},
+	"csv-report": {
+		Description: "CSV with a header row, for spreadsheets",
+		Template: `{{header "number" "title" "category" "author" "createdAt" "answered" "url"}}
+{{range .}}{{.Number}},{{csv .Title}},{{csv .Category.Name}},{{csv .Author.Login}},{{date .CreatedAt}},{{.IsAnswered}},{{.URL}}
+{{end}}`,
+		Headers: true,
+	},
+	"changelog": {
+		Description: "Markdown list of titles linking to each discussion",
+		Template: `{{range .}}- {{.Title}} ([#{{.Number}}]({{.URL}}))
+{{end}}`,
+	},
+}
+
+// Functions available to preset templates
+var presetFuncs = template.FuncMap{
+	"csv":   csvField,
+	"slack": strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace,
+	"date": func(t time.Time) string {
+		return t.Format("2006-01-02")
+	},
+}
+
+// Quote a CSV field if it needs it
+func csvField(s string) string {
+	if strings.ContainsAny(s, "\",\r\n") {
+		return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+	}
+	return s
+}
+
+// Sorted names of the available presets
+func presetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Print the matches using the named preset, with column headers renamed by
+// --field-alias
+func outputPreset(matches []Discussion, name string, flags Flags) error {
+	header := func(names ...string) string {
+		headers := make([]string, len(names))
+		for i, name := range names {
+			headers[i] = csvField(fieldHeader(name, flags.fieldAliases))
+		}
+		return strings.Join(headers, ",")
+	}
+	tmpl, err := template.New(name).Funcs(presetFuncs).Funcs(template.FuncMap{"header": header}).Parse(presets[name].Template)
+	if err != nil {
+		return fmt.Errorf("could not parse preset %q: %w", name, err)
+	}
+	if err := tmpl.Execute(stdout, matches); err != nil {
+		return fmt.Errorf("could not render preset %q: %w", name, err)
+	}
+	return nil
+}
+
+// Print the available presets
+func listPresets() error {
+	tp := tableprinter.New(stdout, term.IsTerminal(os.Stdout), 100)
+	for _, name := range presetNames() {
+		tp.AddField(name)
+		tp.AddField(presets[name].Description)
+		tp.EndRow()
+	}
+	return tp.Render()
+}
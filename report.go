@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Count how often each word of the search term occurs in a discussion
+func countTermHits(d Discussion, search string, flags Flags) map[string]int {
+	hits := map[string]int{}
+	for _, term := range strings.Fields(search) {
+		hits[term] = countMatches(d.Title, term, flags) +
+			countMatches(proseText(d.Body, flags), term, flags) +
+			countInComments(d.Comments, term, flags)
+	}
+	return hits
+}
+
+// Print, per search term, the total hits and the number of matches it hit
+func printMatchReport(out io.Writer, matches []Discussion, flags Flags) {
+	fmt.Fprintln(out, "\nMatch report:")
+	for _, term := range strings.Fields(normalizeText(flags.searchTerm, flags)) {
+		total, discussions := 0, 0
+		for _, d := range matches {
+			if n := d.TermHits[term]; n > 0 {
+				total += n
+				discussions++
+			}
+		}
+		fmt.Fprintf(out, "  %s: %d hits in %d of %d matches\n", term, total, discussions, len(matches))
+	}
+}
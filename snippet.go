@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Leading snippets are cut to this many characters
+const snippetLength = 80
+
+// Body snippet for the table according to --snippet
+func snippet(body string, flags Flags) string {
+	if flags.snippet == "best" {
+		if sentence := bestSentence(body, flags); sentence != "" {
+			return sentence
+		}
+	}
+	return leadSnippet(body)
+}
+
+// The start of the body on a single line
+func leadSnippet(body string) string {
+	line := strings.Join(strings.Fields(body), " ")
+	if utf8.RuneCountInString(line) <= snippetLength {
+		return line
+	}
+	runes := []rune(line)
+	return strings.TrimSpace(string(runes[:snippetLength])) + "…"
+}
+
+// The sentence of the body containing the search term most often, or the
+// most of its words; empty when no sentence contains any of them
+func bestSentence(body string, flags Flags) string {
+	search := normalizeText(flags.searchTerm, flags)
+	if search == "" {
+		return ""
+	}
+	terms := strings.Fields(search)
+
+	best, bestScore := "", 0
+	for _, sentence := range splitSentences(body) {
+		normalized := normalizeText(sentence, flags)
+		// A hit on the whole term outweighs hits on its individual words
+		score := strings.Count(normalized, search) * (len(terms) + 1)
+		for _, t := range terms {
+			if strings.Contains(normalized, t) {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = sentence, score
+		}
+	}
+	return best
+}
+
+// Split text into single-line sentences at terminal punctuation and blank lines
+func splitSentences(text string) []string {
+	sentences := []string{}
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		words := strings.Fields(paragraph)
+		start := 0
+		for i, w := range words {
+			if strings.HasSuffix(w, ".") || strings.HasSuffix(w, "!") || strings.HasSuffix(w, "?") || i == len(words)-1 {
+				sentences = append(sentences, strings.Join(words[start:i+1], " "))
+				start = i + 1
+			}
+		}
+	}
+	return sentences
+}
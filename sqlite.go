@@ -0,0 +1,111 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	// Pure-Go SQLite driver, so gh-ask still builds without cgo
+	_ "modernc.org/sqlite"
+)
+
+const createDiscussionsTable = `CREATE TABLE IF NOT EXISTS discussions (
+	repo TEXT NOT NULL,
+	number INTEGER NOT NULL,
+	title TEXT NOT NULL,
+	url TEXT NOT NULL,
+	body TEXT NOT NULL,
+	created_at TEXT NOT NULL,
+	last_active_at TEXT NOT NULL,
+	author TEXT NOT NULL,
+	author_name TEXT,
+	category TEXT NOT NULL,
+	category_emoji TEXT NOT NULL,
+	category_emoji_html TEXT NOT NULL,
+	category_is_answerable INTEGER NOT NULL,
+	labels TEXT NOT NULL,
+	pinned INTEGER NOT NULL,
+	is_answered INTEGER NOT NULL,
+	upvote_count INTEGER NOT NULL,
+	comment_count INTEGER NOT NULL,
+	reactions INTEGER NOT NULL,
+	score REAL NOT NULL,
+	match_location TEXT,
+	answer_chosen_at TEXT,
+	answer_body TEXT,
+	answer_url TEXT,
+	answer_author TEXT,
+	comments TEXT,
+	PRIMARY KEY (repo, number)
+)`
+
+const insertDiscussion = `INSERT OR REPLACE INTO discussions (
+	repo, number, title, url, body, created_at, last_active_at, author,
+	author_name, category, category_emoji, category_emoji_html,
+	category_is_answerable, labels, pinned, is_answered, upvote_count,
+	comment_count, reactions, score, match_location, answer_chosen_at,
+	answer_body, answer_url, answer_author, comments
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+// Write discussions of repo ("owner/name") into the discussions table of a
+// SQLite database, replacing rows for discussions that were exported before
+func exportSQLite(path, repo string, discussions []Discussion) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return fmt.Errorf("could not open SQLite database: %w", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(createDiscussionsTable); err != nil {
+		return fmt.Errorf("could not create discussions table: %w", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("could not start SQLite transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, d := range discussions {
+		labels := []string{}
+		for _, l := range d.Labels.Nodes {
+			labels = append(labels, l.Name)
+		}
+		var answerChosenAt, answerBody, answerURL, answerAuthor, comments sql.NullString
+		if d.AnswerChosenAt != nil {
+			answerChosenAt = sql.NullString{String: d.AnswerChosenAt.Format(time.RFC3339), Valid: true}
+		}
+		if d.Answer != nil {
+			answerBody = sql.NullString{String: d.Answer.Body, Valid: true}
+			answerURL = sql.NullString{String: d.Answer.URL, Valid: true}
+			answerAuthor = sql.NullString{String: d.Answer.Author.Login, Valid: true}
+		}
+		if d.Comments != nil {
+			encoded, err := json.Marshal(d.Comments.Nodes)
+			if err != nil {
+				return fmt.Errorf("could not serialize comments: %w", err)
+			}
+			comments = sql.NullString{String: string(encoded), Valid: true}
+		}
+		_, err := tx.Exec(insertDiscussion,
+			repo, d.Number, d.Title, d.URL, d.Body,
+			d.CreatedAt.Format(time.RFC3339), d.LastActive().Format(time.RFC3339),
+			d.Author.Login, sql.NullString{String: d.Author.Name, Valid: d.Author.Name != ""},
+			d.Category.Name, d.Category.Emoji, d.Category.EmojiHTML, d.Category.IsAnswerable, strings.Join(labels, ","),
+			d.Pinned, d.IsAnswered, d.UpvoteCount, d.CommentCount.TotalCount, d.Reactions.TotalCount, d.Score,
+			sql.NullString{String: d.MatchLocation, Valid: d.MatchLocation != ""},
+			answerChosenAt, answerBody, answerURL, answerAuthor, comments)
+		if err != nil {
+			return fmt.Errorf("could not insert discussion %d: %w", d.Number, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not write SQLite database: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %d discussions to %s\n", len(discussions), path)
+	return nil
+}
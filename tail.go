@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/cli/go-gh/pkg/api"
+	"github.com/cli/go-gh/pkg/repository"
+)
+
+// Shortest --interval accepted, to stay well within API rate limits
+const minTailInterval = 10 * time.Second
+
+// Print matches, then keep polling and print each new match as it appears
+// until interrupted
+func runTail(client api.GQLClient, repo repository.Repository, flags Flags) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	seen := map[int]bool{}
+	reported := map[string]bool{}
+	for poll := 0; ; poll++ {
+		var warnings Warnings
+		result, err := searchDiscussions(client, repo, flags, &warnings)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			// Only the initial search is fatal; later polls retry
+			if poll == 0 {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "poll failed: %v\n", err)
+		}
+
+		// Oldest first, so the newest match ends up last like tail -f
+		matches := result.Matches
+		sortDiscussions(matches, "oldest")
+		for _, d := range matches {
+			if seen[d.Number] {
+				continue
+			}
+			seen[d.Number] = true
+			if err := printTailLine(d, flags); err != nil {
+				return err
+			}
+		}
+
+		// Report each distinct warning once
+		if !flags.quiet {
+			for _, w := range warnings {
+				if !reported[w] {
+					reported[w] = true
+					fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(flags.interval):
+		}
+	}
+}
+
+// Print one match as a line, or as one JSON object per line with --json
+func printTailLine(d Discussion, flags Flags) error {
+	if flags.jsonFlag {
+		if err := json.NewEncoder(stdout).Encode(d); err != nil {
+			return fmt.Errorf("could not serialize JSON: %w", err)
+		}
+		return nil
+	}
+	_, err := fmt.Fprintf(stdout, "%s\t%s\t%s\n",
+		d.CreatedAt.Local().Format("2006-01-02 15:04"), discussionTitle(d, flags), d.URL)
+	return err
+}